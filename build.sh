@@ -5,21 +5,25 @@
 
 echo "Building goBodyFile..."
 
+# Version recorded in the collection manifests.
+VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+LDFLAGS="-X gobodyfile/common.Version=${VERSION}"
+
 # Build for current platform
 echo "Building for current platform..."
-go build -o gobodyfile
+go build -ldflags "${LDFLAGS}" -o gobodyfile
 
 # Build for Linux
 echo "Building for Linux..."
-GOOS=linux GOARCH=amd64 go build -o gobodyfile-linux-amd64
+GOOS=linux GOARCH=amd64 go build -ldflags "${LDFLAGS}" -o gobodyfile-linux-amd64
 
 # Build for Windows
 echo "Building for Windows..."
-GOOS=windows GOARCH=amd64 go build -o gobodyfile-windows-amd64.exe
+GOOS=windows GOARCH=amd64 go build -ldflags "${LDFLAGS}" -o gobodyfile-windows-amd64.exe
 
 # Build for macOS
 echo "Building for macOS..."
-GOOS=darwin GOARCH=amd64 go build -o gobodyfile-darwin-amd64
+GOOS=darwin GOARCH=amd64 go build -ldflags "${LDFLAGS}" -o gobodyfile-darwin-amd64
 
 echo "Build complete!"
 echo "Generated binaries:"
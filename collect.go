@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gobodyfile/common"
+	"gobodyfile/createBody"
+)
+
+/*
+Collects the metadata of the files under the directories into a body file.
+*/
+func runCollect(args []string) {
+
+	fs := newFlagSet("collect", "", `Examples:
+  gobodyfile collect -directory /etc -directory '/home/*/.ssh' -md5 -output triage.body
+  gobodyfile collect -profile web-server -package web01.zip
+  find / -newer /var/log/wtmp -print0 | gobodyfile collect -paths-from - -output recent.body -force`)
+
+	// Directories that will be searched.
+	var rootDirs []string
+	var outputFile string
+	var watch bool
+	var profile string
+	var opts createBody.Options
+
+	// Pass the names of the directories via the commandline.
+	fs.Func("directory", "Directory containing the files to collect metadata. Can be repeated and can be a glob like /home/*/.ssh.", func(dir string) error {
+		rootDirs = append(rootDirs, dir)
+		return nil
+	})
+	fs.StringVar(&opts.PathsFrom, "paths-from", "", "(Optional) File listing more paths to collect, one per line or NUL separated (find -print0). Use - to read stdin.")
+	fs.StringVar(&outputFile, "output", "", "Output file name")
+	fs.BoolVar(&opts.SID, "sid", false, "(Optional) Display the SID. Default will return the UID and GID.")
+	fs.BoolVar(&opts.MD5, "md5", false, "(Optional) Compute the MD5 of regular files.")
+	fs.BoolVar(&opts.ErrorJSON, "error-json", false, "(Optional) Write the error log as JSON Lines (<output>.errors.jsonl).")
+	fs.StringVar(&opts.Compress, "compress", "", "(Optional) Compress the output with gzip or zstd. Default picks it from the -output extension (.gz, .zst).")
+	fs.Var((*common.StringList)(&opts.Recipients), "recipient", "(Optional) age public key to encrypt the output to. Can be repeated.")
+	fs.StringVar(&opts.RecipientsFile, "recipients-file", "", "(Optional) File of age public keys to encrypt the output to.")
+	fs.StringVar(&opts.Identity, "identity", "", "(Optional) age identity file to read an encrypted -since-body.")
+	fs.StringVar(&opts.Package, "package", "", "(Optional) Zip archive to write the body file, error log, summary and manifest to. -output names the body file inside it.")
+	fs.BoolVar(&opts.Count, "count", false, "(Optional, Windows) Walk the directories twice to count the paths and show an ETA in the progress line. Linux and macOS always count them while walking.")
+	fs.StringVar(&opts.SinceBody, "since-body", "", "(Optional) Previous body file. Only new or changed entries and tombstones for deleted entries are written.")
+	fs.BoolVar(&opts.JSON, "json", false, "(Optional) Write JSON Lines instead of body file lines.")
+	fs.BoolVar(&opts.Strict, "strict", false, "(Optional) Exit with an error when permission, I/O, disk full or write errors occur.")
+	fs.BoolVar(&opts.Resume, "resume", false, "(Optional) Continue an interrupted collection into the same output file using its checkpoint.")
+	fs.BoolVar(&opts.Xattrs, "xattrs", false, "(Optional, Linux only) Write extended attributes, ACLs, security labels and file capabilities to <output>.xattrs.jsonl.")
+	fs.StringVar(&opts.MountPrefix, "mount-prefix", "", "(Optional) Write names as they were on the original system, like fls -m: the -strip-prefix (default the directory) is replaced with this prefix (e.g. / or C:/).")
+	fs.StringVar(&opts.StripPrefix, "strip-prefix", "", "(Optional) Prefix removed from the names, like the mount point of an image (e.g. /mnt/evidence).")
+	fs.BoolVar(&opts.NormalizePaths, "normalize-paths", false, "(Optional) Write backslashes in names as slashes.")
+	fs.BoolVar(&opts.Owners, "owners", false, "(Optional) Write the account and group names of the UIDs and GIDs to <output>.owners.json.")
+	fs.StringVar(&opts.OwnersRoot, "owners-root", "", "(Optional) Root of the system whose etc/passwd and etc/group are used by -owners, like a mounted image. Default is the live system.")
+	fs.StringVar(&profile, "profile", "", "(Optional) Collection profile with the directories, includes, excludes, hashing and depth for a kind of incident: linux-triage, web-server, persistence or one from the config file. Use -profile list to show them.")
+	fs.Var((*common.StringList)(&opts.Include), "include", "(Optional) Only collect the files matching this pattern, like *.php. A pattern with a slash matches the whole path. Can be repeated.")
+	fs.Var((*common.StringList)(&opts.Exclude), "exclude", "(Optional) Leave out the paths matching this pattern, like node_modules or *.log. A pattern with a slash matches the whole path. Can be repeated.")
+	fs.IntVar(&opts.MaxDepth, "max-depth", 0, "(Optional) Only walk this many directories deep under each directory. 0 walks everything.")
+	fs.Int64Var(&opts.HashMaxSize, "hash-max-size", 0, "(Optional) Don't hash files bigger than this many bytes with -md5. 0 hashes every file.")
+	fs.BoolVar(&opts.Entropy, "entropy", false, "(Optional) Compute the Shannon entropy of regular files (0 to 8 bits per byte, sampled above 1 MiB) and write it in an extended column.")
+	fs.BoolVar(&opts.SSDeep, "ssdeep", false, "(Optional) Compute the ssdeep fuzzy hash of regular files bigger than 4096 bytes and write it in an extended column. -hash-max-size applies.")
+	fs.BoolVar(&opts.Types, "types", false, "(Optional) Detect the type of regular files from their first 4 KB (elf, pe, pdf, zip, script...) and write it in an extended column.")
+	fs.BoolVar(&opts.Force, "force", false, "(Optional) Replace the output file when it exists.")
+	fs.BoolVar(&opts.Append, "append", false, "(Optional) Add the entries to the output file when it exists, without a new header.")
+	fs.BoolVar(&watch, "watch", false, "(Optional, Linux only) Keep running and append an entry each time a path under the directory changes.")
+
+	parseFlags(fs, args)
+
+	// Show the profiles.
+	if profile == "list" {
+
+		profiles, err := createBody.Profiles(configFile(fs))
+		if err != nil {
+			fmt.Printf("Could not read the profiles: %v\n", err)
+			os.Exit(1)
+		}
+		for _, p := range profiles {
+			fmt.Printf("%-14s %s\n", p.Name, p.Description)
+		}
+		return
+
+	}
+
+	// The profile fills in what wasn't given.
+	if profile != "" {
+
+		p, err := createBody.LoadProfile(profile, configFile(fs))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		// Options set on the command line, in the environment or the configuration win.
+		given := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) {
+			given[f.Name] = true
+		})
+		rootDirs = p.Apply(rootDirs, &opts, given)
+
+	}
+
+	// Check if the root value is empty.
+	if len(rootDirs) == 0 && opts.PathsFrom == "" {
+
+		fmt.Println("Directory not provided. Use -directory and provide the directory name, or -paths-from.")
+		return
+
+	}
+
+	// The default -strip-prefix is the directory, so there has to be only one.
+	if opts.MountPrefix != "" && opts.StripPrefix == "" && (len(rootDirs) != 1 || opts.PathsFrom != "") {
+
+		fmt.Println("-mount-prefix with several directories or -paths-from needs -strip-prefix.")
+		return
+
+	}
+
+	// Replacing and adding to the output don't go together.
+	if opts.Force && opts.Append {
+
+		fmt.Println("Use either -force or -append.")
+		os.Exit(2)
+
+	}
+
+	// Giving the accounts root asks for the owners.
+	if opts.OwnersRoot != "" {
+		opts.Owners = true
+	}
+
+	// The body file in a package is named after the package unless -output is given.
+	if outputFile == "" && opts.Package != "" {
+		outputFile = strings.TrimSuffix(filepath.Base(opts.Package), filepath.Ext(opts.Package)) + ".body"
+	}
+
+	// Check if the outputFile value is empty.
+	if outputFile == "" {
+
+		fmt.Println("Output file not provided. Use -output and provide the file's name.")
+		return
+
+	}
+
+	// Stream changes instead of taking a snapshot.
+	if watch {
+
+		if len(rootDirs) != 1 || opts.PathsFrom != "" {
+			fmt.Println("-watch takes a single -directory.")
+			return
+		}
+
+		createBody.Watch(rootDirs[0], outputFile, opts)
+		return
+
+	}
+
+	// Create the body file.
+	if err := createBody.CreateBody(rootDirs, outputFile, opts); err != nil {
+
+		os.Exit(1)
+
+	}
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+/*
+columnReader removes the extended columns written after the crtime of each entry, so body files
+with them can be read as the 11 fields of the TSK format.
+*/
+type columnReader struct {
+	br      *bufio.Reader
+	columns []string
+	record  func(name string, values map[string]string)
+	pending string
+	err     error
+}
+
+/*
+StripColumns returns a reader of the entries of r without the extended columns named in the
+header. record is called with the column values of each entry by name when it isn't nil. Comment
+lines are left as they are.
+*/
+func StripColumns(r io.Reader, columns []string, record func(name string, values map[string]string)) io.Reader {
+
+	if len(columns) == 0 {
+		return r
+	}
+
+	return &columnReader{br: bufio.NewReader(r), columns: columns, record: record}
+}
+
+/*
+Reads the entries a line at a time.
+*/
+func (c *columnReader) Read(p []byte) (int, error) {
+
+	for c.pending == "" && c.err == nil {
+		line, err := c.br.ReadString('\n')
+		c.pending = c.strip(line)
+		c.err = err
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+
+	if c.pending == "" && c.err != nil {
+		return n, c.err
+	}
+
+	return n, nil
+}
+
+/*
+Removes the columns from a line, keeping its line ending.
+*/
+func (c *columnReader) strip(line string) string {
+
+	text := strings.TrimRight(line, "\r\n")
+	if text == "" || strings.HasPrefix(text, "#") {
+		return line
+	}
+
+	// Lines too short to have the columns are left for the body file reader to reject.
+	fields := strings.Split(text, "|")
+	n := len(fields) - len(c.columns)
+	if n < 11 {
+		return line
+	}
+
+	if c.record != nil {
+		values := make(map[string]string, len(c.columns))
+		for i, column := range c.columns {
+			values[column] = fields[n+i]
+		}
+		// Everything between the MD5 and the last nine fields is the name.
+		c.record(strings.Join(fields[1:n-9], "|"), values)
+	}
+
+	return strings.Join(fields[:n], "|") + line[len(text):]
+}
+
+/*
+BodyEntries reads the header of a body file and returns a reader of its entries without the
+extended columns, for readers that only need the 11 fields.
+*/
+func BodyEntries(r io.Reader) (io.Reader, error) {
+
+	header, entries, err := ReadHeader(r)
+	if err != nil || header == nil {
+		return entries, err
+	}
+
+	return StripColumns(entries, header.Columns, nil), nil
+}
@@ -0,0 +1,34 @@
+package common
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripColumns(t *testing.T) {
+	input := "# gobodyfile body file\n" +
+		"0|/srv/a.pdf|1|33188|0|0|3|1|2|3|4|elf\r\n" +
+		"#deleted|0|/srv/b|2|33188|0|0|3|1|2|3|4|\n" +
+		"0|/srv/c|d|3|33188|0|0|3|1|2|3|4|text"
+	want := "# gobodyfile body file\n" +
+		"0|/srv/a.pdf|1|33188|0|0|3|1|2|3|4\r\n" +
+		"#deleted|0|/srv/b|2|33188|0|0|3|1|2|3|4|\n" +
+		"0|/srv/c|d|3|33188|0|0|3|1|2|3|4"
+
+	values := make(map[string]map[string]string)
+	r := StripColumns(strings.NewReader(input), []string{"type"}, func(name string, v map[string]string) {
+		values[name] = v
+	})
+
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != want {
+		t.Errorf("StripColumns() = %q, %v, want %q", got, err, want)
+	}
+
+	wantValues := map[string]map[string]string{"/srv/a.pdf": {"type": "elf"}, "/srv/c|d": {"type": "text"}}
+	if !reflect.DeepEqual(values, wantValues) {
+		t.Errorf("StripColumns() recorded %v, want %v", values, wantValues)
+	}
+}
@@ -0,0 +1,266 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+Config holds the tables of a configuration file by name, like "profile.web-server". It reads the
+part of TOML the settings need: [table] headers, key = value pairs with strings, integers,
+booleans and arrays of strings, and # comments. Values are a string, int64, bool or []string.
+*/
+type Config map[string]map[string]any
+
+/*
+DefaultConfigFile returns the configuration file in the user's configuration directory, like
+~/.config/gobodyfile/config.toml. It is empty when there is no such directory.
+*/
+func DefaultConfigFile() string {
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "gobodyfile", "config.toml")
+}
+
+/*
+LoadConfig reads a configuration file. A missing file is an empty configuration.
+*/
+func LoadConfig(path string) (Config, error) {
+
+	if path == "" {
+		return Config{}, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config, err := ReadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+/*
+ReadConfig reads a configuration. Keys before the first table header are in the "" table.
+*/
+func ReadConfig(r io.Reader) (Config, error) {
+
+	config := Config{"": {}}
+	table := ""
+
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header", line)
+			}
+			parts := strings.Split(text[1:len(text)-1], ".")
+			for i, part := range parts {
+				parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+			}
+			table = strings.Join(parts, ".")
+			if _, ok := config[table]; !ok {
+				config[table] = make(map[string]any)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value = strings.TrimSpace(value)
+
+		// An array can go on over several lines.
+		start := line
+		for strings.HasPrefix(value, "[") && !closedArray(value) && scanner.Scan() {
+			line++
+			value += " " + strings.TrimSpace(stripComment(scanner.Text()))
+		}
+
+		parsed, err := parseConfigValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", start, key, err)
+		}
+		config[table][key] = parsed
+	}
+
+	return config, scanner.Err()
+}
+
+/*
+Removes a # comment that isn't inside a string.
+*/
+func stripComment(line string) string {
+
+	var quote rune
+	escaped := false
+
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+/*
+Checks if an array value has its closing bracket.
+*/
+func closedArray(value string) bool {
+	return strings.HasSuffix(strings.TrimSpace(value), "]")
+}
+
+/*
+Parses a string, integer, boolean or array of strings.
+*/
+func parseConfigValue(value string) (any, error) {
+
+	switch {
+	case value == "true":
+		return true, nil
+
+	case value == "false":
+		return false, nil
+
+	case strings.HasPrefix(value, `"`), strings.HasPrefix(value, "'"):
+		s, rest, err := parseConfigString(value)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after the string", rest)
+		}
+		return s, nil
+
+	case strings.HasPrefix(value, "["):
+		return parseConfigArray(value)
+	}
+
+	n, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s", value)
+	}
+
+	return n, nil
+}
+
+/*
+Parses the string at the start of value, "basic" with escapes or 'literal', and returns the rest.
+*/
+func parseConfigString(value string) (string, string, error) {
+
+	quote := value[0]
+
+	if quote == '\'' {
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return value[1 : end+1], value[end+2:], nil
+	}
+
+	var b strings.Builder
+
+	for i := 1; i < len(value); i++ {
+
+		c := value[i]
+		if c == '"' {
+			return b.String(), value[i+1:], nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(value) {
+			break
+		}
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '\\', '"':
+			b.WriteByte(value[i])
+		default:
+			return "", "", fmt.Errorf("unknown escape \\%c", value[i])
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+/*
+Parses an array of strings.
+*/
+func parseConfigArray(value string) ([]string, error) {
+
+	items := []string{}
+	rest := strings.TrimSpace(value[1:])
+
+	for {
+
+		if strings.HasPrefix(rest, "]") {
+			if strings.TrimSpace(rest[1:]) != "" {
+				return nil, fmt.Errorf("unexpected %q after the array", rest[1:])
+			}
+			return items, nil
+		}
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			return nil, fmt.Errorf("arrays can only hold strings")
+		}
+
+		item, after, err := parseConfigString(rest)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		rest = strings.TrimSpace(after)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, fmt.Errorf("expected , or ] in the array")
+		}
+	}
+}
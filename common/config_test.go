@@ -0,0 +1,58 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	input := `# defaults
+output = "case.body"
+
+[profile.web-server]
+description = 'Web roots # and logs'
+directories = [
+  "/var/www",   # document roots
+  "/etc/nginx",
+]
+md5 = true
+hash_max_size = 20_000_000
+
+[ profile."my triage" ]
+exclude = ["*.log", "C:\\pagefile.sys"]
+`
+
+	config, err := ReadConfig(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Config{
+		"": {"output": "case.body"},
+		"profile.web-server": {
+			"description":   "Web roots # and logs",
+			"directories":   []string{"/var/www", "/etc/nginx"},
+			"md5":           true,
+			"hash_max_size": int64(20000000),
+		},
+		"profile.my triage": {"exclude": []string{"*.log", `C:\pagefile.sys`}},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("ReadConfig() = %v, want %v", config, want)
+	}
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	for _, input := range []string{
+		"[profile",
+		"key",
+		`key = "open`,
+		"key = [1, 2]",
+		"key = maybe",
+	} {
+		if _, err := ReadConfig(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadConfig(%q) didn't fail", input)
+		}
+	}
+}
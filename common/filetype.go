@@ -0,0 +1,197 @@
+package common
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bytes read from the start of a file to detect its type.
+const SniffSize = 4096
+
+// Magic bytes at the start of a file and the type they identify, checked in order.
+var magicTypes = []struct {
+	magic    string
+	fileType string
+}{
+	{"\x7fELF", "elf"},
+	{"MZ", "pe"},
+	{"\xfe\xed\xfa\xce", "macho"},
+	{"\xfe\xed\xfa\xcf", "macho"},
+	{"\xce\xfa\xed\xfe", "macho"},
+	{"\xcf\xfa\xed\xfe", "macho"},
+	{"\xca\xfe\xba\xbe", "macho"},
+	{"%PDF-", "pdf"},
+	{"PK\x03\x04", "zip"},
+	{"PK\x05\x06", "zip"},
+	{"\x1f\x8b", "gzip"},
+	{"BZh", "bzip2"},
+	{"\xfd7zXZ\x00", "xz"},
+	{"\x28\xb5\x2f\xfd", "zstd"},
+	{"7z\xbc\xaf\x27\x1c", "7z"},
+	{"Rar!\x1a\x07", "rar"},
+	{"\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1", "ole"},
+	{"{\\rtf", "rtf"},
+	{"\x89PNG\r\n\x1a\n", "png"},
+	{"\xff\xd8\xff", "jpeg"},
+	{"GIF87a", "gif"},
+	{"GIF89a", "gif"},
+	{"II*\x00", "tiff"},
+	{"MM\x00*", "tiff"},
+	{"\x00\x00\x01\x00", "ico"},
+	{"SQLite format 3\x00", "sqlite"},
+	{"\x00asm", "wasm"},
+	{"\xd4\xc3\xb2\xa1", "pcap"},
+	{"\xa1\xb2\xc3\xd4", "pcap"},
+	{"\x0a\x0d\x0d\x0a", "pcap"},
+	{"L\x00\x00\x00\x01\x14\x02\x00", "lnk"},
+	{"ElfFile\x00", "evtx"},
+	{"#!", "script"},
+}
+
+/*
+DetectType returns the type of a file from its first bytes: elf, pe, macho, pdf, zip, gzip, png,
+jpeg, script (a #! line), php, html, xml, text and others. It is empty for an empty file and data
+when the content isn't recognized.
+*/
+func DetectType(data []byte) string {
+
+	if len(data) == 0 {
+		return ""
+	}
+
+	// Universal Mach-O binaries and Java classes share their magic, classes have a big version.
+	if bytes.HasPrefix(data, []byte("\xca\xfe\xba\xbe")) && len(data) >= 8 && data[7] >= 45 {
+		return "class"
+	}
+
+	for _, m := range magicTypes {
+		if bytes.HasPrefix(data, []byte(m.magic)) {
+			return m.fileType
+		}
+	}
+
+	if len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		return "webp"
+	}
+	// Bitmaps have reserved zero bytes after the file size, so text starting with BM isn't one.
+	if len(data) >= 14 && string(data[:2]) == "BM" && string(data[6:10]) == "\x00\x00\x00\x00" {
+		return "bmp"
+	}
+	if len(data) >= 262 && string(data[257:262]) == "ustar" {
+		return "tar"
+	}
+
+	// Text in UTF-16 starts with a byte order mark.
+	if bytes.HasPrefix(data, []byte("\xff\xfe")) || bytes.HasPrefix(data, []byte("\xfe\xff")) {
+		return "text"
+	}
+
+	if !isTextContent(data) {
+		return "data"
+	}
+
+	start := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(string(data[:min(len(data), 512)]), "\ufeff")))
+	switch {
+	case strings.Contains(start, "<?php"):
+		return "php"
+	case strings.HasPrefix(start, "<!doctype html"), strings.HasPrefix(start, "<html"):
+		return "html"
+	case strings.HasPrefix(start, "<?xml"), strings.HasPrefix(start, "<svg"):
+		return "xml"
+	}
+
+	return "text"
+}
+
+/*
+Checks if the data is text without NUL bytes: UTF-8, or single-byte text like Latin-1 and
+Windows-1252 whose bytes are all printable.
+*/
+func isTextContent(data []byte) bool {
+
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+
+	return isUTF8Text(data) || isSingleByteText(data)
+}
+
+/*
+Checks if the data is valid UTF-8. A character cut at the end is ignored.
+*/
+func isUTF8Text(data []byte) bool {
+
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			return len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+		data = data[size:]
+	}
+
+	return true
+}
+
+/*
+Checks if every byte is printable in Latin-1 or Windows-1252, or is a tab, line break or form feed.
+Binary data has control bytes.
+*/
+func isSingleByteText(data []byte) bool {
+
+	for _, b := range data {
+		switch {
+		case b == '\t', b == '\n', b == '\v', b == '\f', b == '\r':
+		case b < 0x20, b == 0x7f:
+			return false
+		// Undefined in Windows-1252.
+		case b == 0x81, b == 0x8d, b == 0x8f, b == 0x90, b == 0x9d:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Types of text files.
+var textTypes = []string{"text", "script", "html", "xml", "php"}
+
+// Types expected for the content of the usual extensions.
+var extensionTypes = map[string][]string{
+	".exe": {"pe"}, ".dll": {"pe"}, ".sys": {"pe"}, ".scr": {"pe"}, ".cpl": {"pe"}, ".ocx": {"pe"},
+	".so": {"elf"}, ".o": {"elf"}, ".ko": {"elf"},
+	".dylib": {"macho"},
+	".class": {"class"}, ".wasm": {"wasm"},
+	".pdf": {"pdf"},
+	".zip": {"zip"}, ".jar": {"zip"}, ".war": {"zip"}, ".apk": {"zip"}, ".docx": {"zip"}, ".xlsx": {"zip"}, ".pptx": {"zip"}, ".odt": {"zip"}, ".ods": {"zip"}, ".epub": {"zip"},
+	".doc": {"ole", "rtf"}, ".xls": {"ole"}, ".ppt": {"ole"}, ".msi": {"ole"}, ".rtf": {"rtf"},
+	".gz": {"gzip"}, ".tgz": {"gzip"}, ".bz2": {"bzip2"}, ".xz": {"xz"}, ".zst": {"zstd"}, ".7z": {"7z"}, ".rar": {"rar"}, ".tar": {"tar"},
+	".png": {"png"}, ".jpg": {"jpeg"}, ".jpeg": {"jpeg"}, ".gif": {"gif"}, ".bmp": {"bmp"}, ".webp": {"webp"}, ".tif": {"tiff"}, ".tiff": {"tiff"}, ".ico": {"ico", "png"},
+	".sqlite": {"sqlite"}, ".pcap": {"pcap"}, ".pcapng": {"pcap"}, ".lnk": {"lnk"}, ".evtx": {"evtx"},
+	".txt": textTypes, ".log": textTypes, ".csv": textTypes, ".json": textTypes, ".md": textTypes,
+	".conf": textTypes, ".cfg": textTypes, ".ini": textTypes, ".yml": textTypes, ".yaml": textTypes,
+	".html": textTypes, ".htm": textTypes, ".xml": textTypes, ".svg": textTypes, ".css": textTypes, ".js": textTypes,
+	".php": textTypes, ".sh": textTypes, ".py": textTypes, ".pl": textTypes, ".rb": textTypes,
+}
+
+/*
+ExtensionMismatch checks if the extension of a name contradicts the detected type of the file,
+like an invoice.pdf that is an ELF or a .jpg holding PHP. Names with an extension that isn't known,
+and files without a detected type, never mismatch.
+*/
+func ExtensionMismatch(name string, fileType string) bool {
+
+	expected, ok := extensionTypes[strings.ToLower(path.Ext(strings.ReplaceAll(name, `\`, "/")))]
+	if !ok || fileType == "" {
+		return false
+	}
+
+	for _, t := range expected {
+		if t == fileType {
+			return false
+		}
+	}
+
+	return true
+}
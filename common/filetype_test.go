@@ -0,0 +1,63 @@
+package common
+
+import "testing"
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"\x7fELF\x02\x01\x01", "elf"},
+		{"MZ\x90\x00\x03", "pe"},
+		{"%PDF-1.7\n", "pdf"},
+		{"PK\x03\x04\x14\x00", "zip"},
+		{"\xff\xd8\xff\xe0\x00\x10JFIF", "jpeg"},
+		{"#!/bin/sh\necho hi\n", "script"},
+		{"GIF89a<?php system($_GET['c']); ?>", "gif"},
+		{"<?php system($_GET['c']); ?>", "php"},
+		{"  <!DOCTYPE html><html>", "html"},
+		{"BMW service notes\n", "text"},
+		{"caf\xc3\xa9 au lait \xc3", "text"},
+		{"\x00\x01\x02\x03\xff", "data"},
+		{"\x93quoted\x94 \x80 20\r\n", "text"},
+		{"\x01\x02\x03\xff", "data"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectType([]byte(tt.data)); got != tt.want {
+			t.Errorf("DetectType(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestLatin1Text(t *testing.T) {
+	// A Latin-1 notes file isn't UTF-8 but is still text, so it doesn't mismatch its extension.
+	data := []byte("Caf\xe9 cr\xe8me br\xfbl\xe9e, no\xebl \xa9 2025\n[section]\nkey=\xe0 voir\n")
+
+	fileType := DetectType(data)
+	if fileType != "text" || ExtensionMismatch("/home/a/notes.txt", fileType) {
+		t.Errorf("DetectType(Latin-1) = %q, mismatch %v, want text without a mismatch", fileType, ExtensionMismatch("/home/a/notes.txt", fileType))
+	}
+}
+
+func TestExtensionMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileType string
+		want     bool
+	}{
+		{"/home/a/invoice.pdf", "elf", true},
+		{"/var/www/uploads/cat.JPG", "php", true},
+		{"/var/www/index.php", "php", false},
+		{"/usr/bin/ls", "elf", false},
+		{"/tmp/notes.txt", "script", false},
+		{`C:\Users\a\report.docx`, "pe", true},
+		{"/tmp/unknown.xyz", "elf", false},
+		{"/tmp/empty.pdf", "", false},
+	}
+	for _, tt := range tests {
+		if got := ExtensionMismatch(tt.name, tt.fileType); got != tt.want {
+			t.Errorf("ExtensionMismatch(%q, %q) = %v, want %v", tt.name, tt.fileType, got, tt.want)
+		}
+	}
+}
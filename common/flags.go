@@ -0,0 +1,158 @@
+package common
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Prefix of the environment variables holding option defaults.
+const envPrefix = "GOBODYFILE_"
+
+// Options meaning the same for every command, the only ones read from GOBODYFILE_<OPTION>. The
+// others, like -strict or -output, are only read from the variable of their command.
+var sharedOptions = map[string]bool{"config": true, "identity": true}
+
+/*
+EnvName returns the environment variable of an option: GOBODYFILE_<COMMAND>_<OPTION>, or
+GOBODYFILE_<OPTION> for every command when command is empty. Dashes become underscores.
+*/
+func EnvName(command string, option string) string {
+
+	name := option
+	if command != "" {
+		name = command + "_" + option
+	}
+
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+/*
+StringList is an option that can be repeated, each value is added to the list as it is, commas
+included.
+*/
+type StringList []string
+
+func (l *StringList) String() string {
+
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+/*
+SetFromEnv sets the options of a command that weren't given on the command line from the
+environment. The variable of the command wins over the one for every command, which is only read
+for the options shared by all of them.
+*/
+func SetFromEnv(fs *flag.FlagSet, command string, lookup func(string) (string, bool)) error {
+
+	given := setFlags(fs)
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+
+		if given[f.Name] || err != nil {
+			return
+		}
+
+		names := []string{EnvName(command, f.Name)}
+		if sharedOptions[f.Name] {
+			names = append(names, EnvName("", f.Name))
+		}
+
+		for _, name := range names {
+			if value, ok := lookup(name); ok {
+				if setErr := fs.Set(f.Name, value); setErr != nil {
+					err = fmt.Errorf("%s: %v", name, setErr)
+				}
+				return
+			}
+		}
+	})
+
+	return err
+}
+
+/*
+SetFromConfig sets the options of a command that weren't given on the command line or in the
+environment from the [<command>] table of the configuration, then from the keys before the first
+table. An unknown key in the command's table is an error so typos don't go unnoticed, the other
+keys are shared between the commands. Arrays set a repeatable option once per item.
+*/
+func SetFromConfig(fs *flag.FlagSet, command string, config Config) error {
+
+	given := setFlags(fs)
+
+	for key := range config[command] {
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("[%s] %s: %s has no such option", command, key, command)
+		}
+	}
+
+	for _, table := range []string{command, ""} {
+
+		for key, value := range config[table] {
+
+			if given[key] || fs.Lookup(key) == nil {
+				continue
+			}
+
+			var values []string
+			switch v := value.(type) {
+			case []string:
+				values = v
+			case string:
+				values = []string{v}
+			case int64:
+				values = []string{strconv.FormatInt(v, 10)}
+			case bool:
+				values = []string{strconv.FormatBool(v)}
+			}
+
+			for _, v := range values {
+				if err := fs.Set(key, v); err != nil {
+					return fmt.Errorf("[%s] %s: %v", command, key, err)
+				}
+			}
+			given[key] = true
+		}
+	}
+
+	return nil
+}
+
+/*
+Returns the options set so far.
+*/
+func setFlags(fs *flag.FlagSet) map[string]bool {
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	return given
+}
+
+/*
+CheckOutputFile checks that an output file can be written without a prompt. An existing file is
+only replaced with force or added to with appending, so nothing is overwritten by accident.
+*/
+func CheckOutputFile(outputFile string, force bool, appending bool) error {
+
+	if _, err := os.Stat(outputFile); err != nil || force || appending {
+		return nil
+	}
+
+	return fmt.Errorf("%s already exists, use -force to replace it", outputFile)
+}
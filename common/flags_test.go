@@ -0,0 +1,68 @@
+package common
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOptionPrecedence(t *testing.T) {
+	fs := flag.NewFlagSet("collect", flag.ContinueOnError)
+	output := fs.String("output", "", "")
+	identity := fs.String("identity", "", "")
+	md5 := fs.Bool("md5", false, "")
+	depth := fs.Int("max-depth", 0, "")
+	var dirs []string
+	fs.Func("directory", "", func(d string) error { dirs = append(dirs, d); return nil })
+
+	if err := fs.Parse([]string{"-output", "cli.body"}); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"GOBODYFILE_COLLECT_OUTPUT": "env.body",
+		"GOBODYFILE_IDENTITY":       "env.key",
+		"GOBODYFILE_COLLECT_MD5":    "true",
+		"GOBODYFILE_MAX_DEPTH":      "7",
+	}
+	lookup := func(name string) (string, bool) { v, ok := env[name]; return v, ok }
+	if err := SetFromEnv(fs, "collect", lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ReadConfig(strings.NewReader("identity = \"config.key\"\nmax-depth = 9\n[collect]\nmd5 = false\nmax-depth = 3\ndirectory = [\"/etc\", \"/tmp\"]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFromConfig(fs, "collect", config); err != nil {
+		t.Fatal(err)
+	}
+
+	if *output != "cli.body" || *identity != "env.key" || !*md5 || *depth != 3 || strings.Join(dirs, ",") != "/etc,/tmp" {
+		t.Errorf("output %q, identity %q, md5 %v, max-depth %d, directories %v", *output, *identity, *md5, *depth, dirs)
+	}
+
+	// A typo in the command's table is reported.
+	config["collect"]["md5s"] = true
+	if err := SetFromConfig(fs, "collect", config); err == nil {
+		t.Error("SetFromConfig() accepted an unknown option")
+	}
+}
+
+func TestCheckOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.body")
+
+	if err := CheckOutputFile(path, false, false); err != nil {
+		t.Errorf("new file: %v", err)
+	}
+
+	os.WriteFile(path, nil, 0644)
+	if err := CheckOutputFile(path, false, false); err == nil {
+		t.Error("an existing file was accepted without -force or -append")
+	}
+	if CheckOutputFile(path, true, false) != nil || CheckOutputFile(path, false, true) != nil {
+		t.Error("-force or -append was refused")
+	}
+}
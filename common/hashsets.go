@@ -0,0 +1,270 @@
+package common
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+/*
+HashSet holds the hashes of a list of known files, like an NSRL RDS export or a list of malware
+hashes. Hashes are kept as bytes so MD5, SHA-1 and SHA-256 lists can be mixed.
+*/
+type HashSet struct {
+	Name   string
+	hashes map[string]struct{}
+	md5s   int
+}
+
+// Columns of a CSV export holding the hashes, in order of preference. Body files record MD5s.
+var hashColumns = []string{"md5", "sha-1", "sha1", "sha-256", "sha256"}
+
+/*
+Len returns the number of hashes in the set.
+*/
+func (s *HashSet) Len() int {
+	return len(s.hashes)
+}
+
+/*
+Contains checks if the set has a hash given in hexadecimal.
+*/
+func (s *HashSet) Contains(hash string) bool {
+
+	key, ok := hashKey(hash)
+	if !ok {
+		return false
+	}
+
+	_, found := s.hashes[key]
+
+	return found
+}
+
+/*
+Returns the bytes of a hexadecimal MD5, SHA-1 or SHA-256.
+*/
+func hashKey(hash string) (string, bool) {
+
+	switch len(hash) {
+	case 32, 40, 64:
+	default:
+		return "", false
+	}
+
+	b, err := hex.DecodeString(hash)
+	if err != nil {
+		return "", false
+	}
+
+	return string(b), true
+}
+
+/*
+LoadHashSet reads a hash set named after its file. It can be an NSRL RDS SQLite database, a CSV
+export with a header naming the MD5, SHA-1 or SHA-256 column like NSRLFile.txt, or a plain list
+with a hash at the start of each line like md5sum writes. A set without MD5s is refused, it would
+never match a body file.
+*/
+func LoadHashSet(path string) (*HashSet, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	var set *HashSet
+	if magic, _ := br.Peek(16); string(magic) == "SQLite format 3\x00" {
+		set, err = readSQLiteHashSet(path)
+	} else {
+		set, err = ReadHashSet(filepath.Base(path), br)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if set.Len() == 0 {
+		return nil, fmt.Errorf("%s: no hashes found", path)
+	}
+	if set.md5s == 0 {
+		return nil, fmt.Errorf("%s: no MD5s found, body files only record MD5s", path)
+	}
+
+	return set, nil
+}
+
+/*
+ReadHashSet reads a CSV export or a plain list of hashes. Empty lines and # comments are skipped.
+*/
+func ReadHashSet(name string, r io.Reader) (*HashSet, error) {
+
+	set := &HashSet{Name: name, hashes: make(map[string]struct{})}
+
+	br := bufio.NewReader(r)
+
+	// The first line tells a CSV export from a plain list.
+	first, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	header, _, _ := strings.Cut(string(first), "\n")
+
+	if column := csvHashColumn(header); column >= 0 {
+		return set, set.readCSV(br, column)
+	}
+
+	scanner := bufio.NewScanner(br)
+	for scanner.Scan() {
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// md5sum escapes the lines of names with a backslash.
+		set.add(strings.TrimPrefix(fields[0], `\`))
+	}
+
+	return set, scanner.Err()
+}
+
+/*
+Returns the hash column of a CSV header, or -1 when the line isn't one.
+*/
+func csvHashColumn(header string) int {
+
+	fields, err := csv.NewReader(strings.NewReader(header)).Read()
+	if err != nil || len(fields) < 2 {
+		return -1
+	}
+
+	for _, name := range hashColumns {
+		for i, field := range fields {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+/*
+Reads the hashes of a column of a CSV export, after its header.
+*/
+func (s *HashSet) readCSV(r io.Reader, column int) error {
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	if _, err := cr.Read(); err != nil {
+		return err
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if column < len(record) {
+			s.add(record[column])
+		}
+	}
+}
+
+/*
+Reads the MD5s of an NSRL RDS SQLite database, from the FILE table or the first table with an md5
+column.
+*/
+func readSQLiteHashSet(path string) (*HashSet, error) {
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&immutable=1")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	table, err := sqliteHashTable(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT md5 FROM "` + table + `"`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := &HashSet{Name: filepath.Base(path), hashes: make(map[string]struct{})}
+
+	for rows.Next() {
+		var hash sql.NullString
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		set.add(hash.String)
+	}
+
+	return set, rows.Err()
+}
+
+/*
+Returns the table of the database holding the MD5s.
+*/
+func sqliteHashTable(db *sql.DB) (string, error) {
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name = 'FILE' DESC, name`)
+	if err != nil {
+		return "", err
+	}
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return "", err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		var count int
+		err := db.QueryRow(`SELECT count(*) FROM pragma_table_info(?) WHERE lower(name) = 'md5'`, table).Scan(&count)
+		if err == nil && count > 0 {
+			return table, nil
+		}
+	}
+
+	return "", fmt.Errorf("no table with an md5 column")
+}
+
+/*
+Adds a hash given in hexadecimal, anything else is skipped.
+*/
+func (s *HashSet) add(hash string) {
+
+	if key, ok := hashKey(strings.TrimSpace(hash)); ok {
+		if _, found := s.hashes[key]; !found && len(key) == 16 {
+			s.md5s++
+		}
+		s.hashes[key] = struct{}{}
+	}
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadHashSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		in    []string
+		out   []string
+	}{
+		{
+			"list",
+			"# known bad\nd41d8cd98f00b204e9800998ecf8427e\n\nDA39A3EE5E6B4B0D3255BFEF95601890AFD80709\nnot a hash\n",
+			[]string{"d41d8cd98f00b204e9800998ecf8427e", "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+			[]string{"0", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		},
+		{
+			"md5sum",
+			"d41d8cd98f00b204e9800998ecf8427e  /etc/empty\n\\0cc175b9c0f1b6a831c399e269772661  /tmp/a\\nb\n",
+			[]string{"D41D8CD98F00B204E9800998ECF8427E", "0cc175b9c0f1b6a831c399e269772661"},
+			[]string{"/etc/empty"},
+		},
+		{
+			"nsrl",
+			"\"SHA-1\",\"MD5\",\"CRC32\",\"FileName\",\"FileSize\",\"ProductCode\",\"OpSystemCode\",\"SpecialCode\"\n" +
+				"\"0000002D9D62AEBE1E0E9DB6C4C4C7C16A163D2C\",\"1D6EBB5A789ABD108FF578263E1F40F3\",\"FFFFFFFF\",\"_sfx_0024._p\",4109,21000,\"358\",\"\"\n",
+			[]string{"1d6ebb5a789abd108ff578263e1f40f3"},
+			[]string{"0000002D9D62AEBE1E0E9DB6C4C4C7C16A163D2C"},
+		},
+	}
+
+	for _, tt := range tests {
+		set, err := ReadHashSet(tt.name, strings.NewReader(tt.input))
+		if err != nil {
+			t.Fatalf("ReadHashSet(%s) error: %v", tt.name, err)
+		}
+		for _, hash := range tt.in {
+			if !set.Contains(hash) {
+				t.Errorf("ReadHashSet(%s) doesn't contain %s", tt.name, hash)
+			}
+		}
+		for _, hash := range tt.out {
+			if set.Contains(hash) {
+				t.Errorf("ReadHashSet(%s) contains %s", tt.name, hash)
+			}
+		}
+	}
+}
+
+func TestLoadHashSet(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"iocs.md5", "d41d8cd98f00b204e9800998ecf8427e\n", true},
+		{"iocs.sha1", "da39a3ee5e6b4b0d3255bfef95601890afd80709\n", false},
+		{"rds.db", "SQLite format 3\x00", false},
+		{"empty.txt", "# nothing\n", false},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		os.WriteFile(path, []byte(tt.content), 0644)
+
+		if _, err := LoadHashSet(path); (err == nil) != tt.ok {
+			t.Errorf("LoadHashSet(%s) error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestLoadSQLiteHashSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "RDS_modern.db")
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE FILE (sha256 TEXT, sha1 TEXT, md5 TEXT, file_name TEXT)`,
+		`INSERT INTO FILE VALUES ('e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855', 'da39a3ee5e6b4b0d3255bfef95601890afd80709', 'D41D8CD98F00B204E9800998ECF8427E', 'empty')`,
+		`INSERT INTO FILE VALUES (NULL, NULL, NULL, 'no hashes')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	set, err := LoadHashSet(path)
+	if err != nil {
+		t.Fatalf("LoadHashSet() error: %v", err)
+	}
+	if set.Name != "RDS_modern.db" || set.Len() != 1 || !set.Contains("d41d8cd98f00b204e9800998ecf8427e") {
+		t.Errorf("LoadHashSet() = %s with %d hashes, want the MD5 of the FILE table", set.Name, set.Len())
+	}
+}
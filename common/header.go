@@ -0,0 +1,201 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// First line of the header written by the collectors.
+const headerTitle = "# gobodyfile body file"
+
+/*
+Header describes where a body file came from. It is written as '#' comment lines at the top of
+the body file, which TSK tools ignore.
+*/
+type Header struct {
+	Tool       string
+	Host       string
+	Root       string
+	Collected  time.Time
+	Timezone   string
+	Precision  string
+	Extensions []string
+	Since      string
+	Profile    string
+	Scope      string
+
+	// Extended columns written after the crtime of each entry, in order.
+	Columns []string
+}
+
+/*
+Writes the header as comment lines.
+*/
+func (h *Header) Write(w io.Writer) error {
+
+	extensions := "none"
+	if len(h.Extensions) > 0 {
+		extensions = strings.Join(h.Extensions, ",")
+	}
+
+	lines := []string{
+		headerTitle,
+		"# tool: " + h.Tool,
+		"# host: " + h.Host,
+		"# root: " + h.Root,
+		"# collected: " + h.Collected.Format(time.RFC3339),
+		"# timezone: " + h.Timezone,
+		"# precision: " + h.Precision,
+		"# extensions: " + extensions,
+	}
+	if h.Since != "" {
+		lines = append(lines, "# since: "+h.Since)
+	}
+	if h.Profile != "" {
+		lines = append(lines, "# profile: "+h.Profile)
+	}
+	if h.Scope != "" {
+		lines = append(lines, "# scope: "+h.Scope)
+	}
+	if len(h.Columns) > 0 {
+		lines = append(lines, "# columns: "+strings.Join(h.Columns, ","))
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+
+	return err
+}
+
+/*
+ReadHeader reads the header at the top of a body file. The returned reader continues with the
+first line after the header. The header is nil when the body file doesn't have one.
+*/
+func ReadHeader(r io.Reader) (*Header, io.Reader, error) {
+
+	br := bufio.NewReader(r)
+
+	first, err := br.Peek(len(headerTitle))
+	if err != nil || string(first) != headerTitle {
+		// Too short or not written by gobodyfile.
+		return nil, br, nil
+	}
+
+	h := &Header{}
+
+	for {
+
+		// Only read lines that are part of the header.
+		next, err := br.Peek(2)
+		if err != nil || string(next) != "# " {
+			break
+		}
+
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, br, err
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "# ")), ": ")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "tool":
+			h.Tool = value
+		case "host":
+			h.Host = value
+		case "root":
+			h.Root = value
+		case "collected":
+			h.Collected, _ = time.Parse(time.RFC3339, value)
+		case "timezone":
+			h.Timezone = value
+		case "precision":
+			h.Precision = value
+		case "extensions":
+			if value != "none" {
+				h.Extensions = strings.Split(value, ",")
+			}
+		case "since":
+			h.Since = value
+		case "profile":
+			h.Profile = value
+		case "scope":
+			h.Scope = value
+		case "columns":
+			h.Columns = strings.Split(value, ",")
+		}
+	}
+
+	return h, br, nil
+}
+
+/*
+Has checks if the body file uses a field extension.
+*/
+func (h *Header) Has(extension string) bool {
+
+	for _, e := range h.Extensions {
+		if e == extension {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Location returns the timezone of the collection host. The IANA name is used when it was recorded,
+otherwise the UTC offset at the time of the collection.
+*/
+func (h *Header) Location() (*time.Location, error) {
+
+	fields := strings.Fields(h.Timezone)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no timezone in the header")
+	}
+
+	if len(fields) == 2 {
+		if loc, err := time.LoadLocation(fields[0]); err == nil {
+			return loc, nil
+		}
+	}
+
+	offset, err := time.Parse("-07:00", fields[len(fields)-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", h.Timezone)
+	}
+	_, seconds := offset.Zone()
+
+	return time.FixedZone("UTC"+fields[len(fields)-1], seconds), nil
+}
+
+/*
+LocalTimezone returns the timezone of this host as the IANA name, when it can be found, and the
+current UTC offset.
+*/
+func LocalTimezone() string {
+
+	offset := time.Now().Format("-07:00")
+
+	name := os.Getenv("TZ")
+	if name == "" {
+		// On Linux and macOS /etc/localtime links to the zoneinfo file of the timezone.
+		if target, err := os.Readlink("/etc/localtime"); err == nil {
+			if i := strings.Index(target, "zoneinfo/"); i >= 0 {
+				name = target[i+len("zoneinfo/"):]
+			}
+		}
+	}
+
+	if name == "" {
+		return offset
+	}
+
+	return name + " " + offset
+}
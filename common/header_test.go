@@ -0,0 +1,60 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadHeader(t *testing.T) {
+	h := &Header{
+		Tool:       "gobodyfile dev (linux/amd64)",
+		Host:       "fileserver01",
+		Root:       "/srv/share",
+		Collected:  time.Date(2026, 10, 19, 3, 25, 57, 0, time.UTC),
+		Timezone:   "Europe/Paris +02:00",
+		Precision:  "seconds",
+		Extensions: []string{"md5", "tombstones"},
+		Since:      "monday.body",
+		Profile:    "web-server",
+		Scope:      "include *.php; max-depth 4",
+		Columns:    []string{"type"},
+	}
+	entry := "0|/srv/share/a|1|420|0|0|3|1|2|3|4\n"
+
+	var buf bytes.Buffer
+	if err := h.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString(entry)
+
+	read, r, err := ReadHeader(&buf)
+	if err != nil || read == nil {
+		t.Fatalf("ReadHeader() = %v, %v", read, err)
+	}
+	if read.Host != h.Host || read.Root != h.Root || !read.Collected.Equal(h.Collected) || read.Since != h.Since || read.Profile != h.Profile || read.Scope != h.Scope || strings.Join(read.Columns, ",") != "type" || !read.Has("tombstones") {
+		t.Errorf("ReadHeader() = %+v, want %+v", read, h)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != entry {
+		t.Errorf("ReadHeader() left %q, want %q", rest, entry)
+	}
+
+	// Body files without a header are read from the start.
+	read, r, _ = ReadHeader(strings.NewReader(entry))
+	if rest, _ := io.ReadAll(r); read != nil || string(rest) != entry {
+		t.Errorf("ReadHeader() without a header = %v, %q", read, rest)
+	}
+}
+
+func TestHeaderLocation(t *testing.T) {
+	h := &Header{Timezone: "+05:30"}
+	loc, err := h.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := time.Unix(0, 0).In(loc).Zone(); offset != 5*3600+30*60 {
+		t.Errorf("Location() offset = %d", offset)
+	}
+}
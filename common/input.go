@@ -0,0 +1,151 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/klauspost/compress/zstd"
+)
+
+// First bytes of the compressed and encrypted formats.
+var (
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	ageMagic      = []byte("age-encryption.org/v1")
+	ageArmorMagic = []byte(armor.Header)
+)
+
+/*
+bodyReader reads the decoded body file and closes every layer when done.
+*/
+type bodyReader struct {
+	io.Reader
+	closers []func() error
+}
+
+/*
+Closes the decoders and the file.
+*/
+func (b *bodyReader) Close() error {
+
+	var first error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if err := b.closers[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+/*
+Decode returns a reader of the plain body file. Compressed (gzip, zstd) and age encrypted input is
+detected from its first bytes. identityFile holds the age identities and is only needed for
+encrypted input.
+*/
+func Decode(r io.Reader, identityFile string) (io.ReadCloser, error) {
+	return decode(r, identityFile, &bodyReader{})
+}
+
+/*
+Decodes the input adding the decoders to b so they are closed with it.
+*/
+func decode(r io.Reader, identityFile string, b *bodyReader) (io.ReadCloser, error) {
+
+	// Encrypted files are usually compressed too, so keep going until the input is plain.
+	for {
+
+		br := bufio.NewReader(r)
+		magic, _ := br.Peek(len(ageArmorMagic))
+
+		switch {
+		case bytes.HasPrefix(magic, ageMagic), bytes.HasPrefix(magic, ageArmorMagic):
+
+			if identityFile == "" {
+				b.Close()
+				return nil, fmt.Errorf("the input is encrypted, use -identity to provide the age identity file")
+			}
+
+			identities, err := readIdentities(identityFile)
+			if err != nil {
+				b.Close()
+				return nil, err
+			}
+
+			var src io.Reader = br
+			if bytes.HasPrefix(magic, ageArmorMagic) {
+				src = armor.NewReader(br)
+			}
+
+			r, err = age.Decrypt(src, identities...)
+			if err != nil {
+				b.Close()
+				return nil, fmt.Errorf("could not decrypt the input: %v", err)
+			}
+
+		case bytes.HasPrefix(magic, gzipMagic):
+
+			zr, err := gzip.NewReader(br)
+			if err != nil {
+				b.Close()
+				return nil, err
+			}
+			b.closers = append(b.closers, zr.Close)
+			r = zr
+
+		case bytes.HasPrefix(magic, zstdMagic):
+
+			zr, err := zstd.NewReader(br)
+			if err != nil {
+				b.Close()
+				return nil, err
+			}
+			b.closers = append(b.closers, func() error { zr.Close(); return nil })
+			r = zr
+
+		default:
+			b.Reader = br
+			return b, nil
+		}
+	}
+}
+
+/*
+OpenBody opens a body file and decodes it when it is compressed or encrypted. For a package, the
+body file in it is read.
+*/
+func OpenBody(path string, identityFile string) (io.ReadCloser, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is closed last, after the decoders.
+	return decodeFile(f, identityFile, &bodyReader{closers: []func() error{f.Close}})
+}
+
+/*
+Reads the age identities from a file.
+*/
+func readIdentities(identityFile string) ([]age.Identity, error) {
+
+	f, err := os.Open(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the identity file: %v", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the identity file: %v", err)
+	}
+
+	return identities, nil
+}
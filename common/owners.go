@@ -0,0 +1,139 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+Owners maps the UIDs and GIDs of a system to its account and group names. It is read from the
+passwd and group files under a root, so a mounted image is resolved with its own accounts, and
+written as the owners sidecar (file.txt.owners.json).
+*/
+type Owners struct {
+	Root   string         `json:"root"`
+	Users  map[int]string `json:"users"`
+	Groups map[int]string `json:"groups"`
+}
+
+/*
+ReadAccounts reads etc/passwd and etc/group under root. "/" is the live system.
+*/
+func ReadAccounts(root string) (*Owners, error) {
+
+	o := &Owners{Root: root}
+
+	var err error
+	if o.Users, err = readIDFile(filepath.Join(root, "etc", "passwd")); err != nil {
+		return nil, err
+	}
+	if o.Groups, err = readIDFile(filepath.Join(root, "etc", "group")); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+/*
+Reads a passwd or group file, both have the name first and the ID third. The first name of an ID
+is kept, the same way getpwuid finds it.
+*/
+func readIDFile(path string) (map[int]string, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(map[int]string)
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 {
+			continue
+		}
+
+		id, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		if _, ok := names[id]; !ok {
+			names[id] = fields[0]
+		}
+	}
+
+	return names, scanner.Err()
+}
+
+/*
+ReadOwners reads an owners sidecar.
+*/
+func ReadOwners(r io.Reader) (*Owners, error) {
+
+	var o Owners
+	if err := json.NewDecoder(r).Decode(&o); err != nil {
+		return nil, fmt.Errorf("invalid owners sidecar: %v", err)
+	}
+
+	return &o, nil
+}
+
+/*
+LoadOwners reads the owners from a root directory, an owners sidecar, or the sidecar in a package.
+*/
+func LoadOwners(source string) (*Owners, error) {
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return ReadAccounts(source)
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if IsPackage(f) {
+		packaged, err := PackageFile(f, ".owners.json")
+		if err != nil {
+			return nil, fmt.Errorf("no owners sidecar in %s", source)
+		}
+		defer packaged.Close()
+		return ReadOwners(packaged)
+	}
+
+	return ReadOwners(f)
+}
+
+/*
+User returns the account name of a UID, or an empty string when it is unknown.
+*/
+func (o *Owners) User(uid int) string {
+	return o.Users[uid]
+}
+
+/*
+Group returns the group name of a GID, or an empty string when it is unknown.
+*/
+func (o *Owners) Group(gid int) string {
+	return o.Groups[gid]
+}
@@ -0,0 +1,31 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadAccounts(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	passwd := "root:x:0:0:root:/root:/bin/bash\n# comment\nwww-data:x:33:33::/var/www:/usr/sbin/nologin\ntoor:x:0:0::/root:/bin/sh\nbroken\n"
+	group := "root:x:0:\nwww-data:x:33:\ndocker:x:999:alice,bob\n"
+	os.WriteFile(filepath.Join(root, "etc", "passwd"), []byte(passwd), 0644)
+	os.WriteFile(filepath.Join(root, "etc", "group"), []byte(group), 0644)
+
+	o, err := ReadAccounts(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o.User(0) != "root" || o.User(33) != "www-data" || o.User(1000) != "" {
+		t.Errorf("users = %v", o.Users)
+	}
+	if o.Group(999) != "docker" || o.Group(33) != "www-data" {
+		t.Errorf("groups = %v", o.Groups)
+	}
+}
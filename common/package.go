@@ -0,0 +1,101 @@
+package common
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// First bytes of a zip archive.
+var zipMagic = []byte("PK\x03\x04")
+
+/*
+IsPackage checks if the file is an evidence package written with -package.
+*/
+func IsPackage(f io.ReaderAt) bool {
+
+	magic := make([]byte, len(zipMagic))
+	if _, err := f.ReadAt(magic, 0); err != nil {
+		return false
+	}
+
+	return bytes.Equal(magic, zipMagic)
+}
+
+/*
+DecodeFile is Decode for a file. Evidence packages are read too, which needs random access, so a
+package can't come from a pipe.
+*/
+func DecodeFile(f *os.File, identityFile string) (io.ReadCloser, error) {
+	return decodeFile(f, identityFile, &bodyReader{})
+}
+
+/*
+Decodes the file, or the body file in it when it is a package. The body file is the first file of
+the package.
+*/
+func decodeFile(f *os.File, identityFile string, b *bodyReader) (io.ReadCloser, error) {
+
+	if !IsPackage(f) {
+		return decode(f, identityFile, b)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("invalid package: %v", err)
+	}
+
+	if len(zr.File) == 0 {
+		b.Close()
+		return nil, fmt.Errorf("the package is empty")
+	}
+
+	body, err := zr.File[0].Open()
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	b.closers = append(b.closers, body.Close)
+
+	// The body file in the package can still be compressed or encrypted.
+	return decode(body, identityFile, b)
+}
+
+/*
+PackageFile opens the file of a package whose name ends with suffix. Returns an error satisfying
+os.IsNotExist when f isn't a package or has no such file.
+*/
+func PackageFile(f *os.File, suffix string) (io.ReadCloser, error) {
+
+	if !IsPackage(f) {
+		return nil, os.ErrNotExist
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("invalid package: %v", err)
+	}
+
+	for _, file := range zr.File {
+		if strings.HasSuffix(file.Name, suffix) {
+			return file.Open()
+		}
+	}
+
+	return nil, os.ErrNotExist
+}
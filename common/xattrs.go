@@ -0,0 +1,302 @@
+package common
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values longer than this are cut in the xattrs sidecar. The full size is kept.
+const XattrValueLimit = 4096
+
+/*
+XattrRecord holds the extended attributes of a path, written as a line of the xattrs sidecar
+(file.txt.xattrs.jsonl). ACLs, security labels and file capabilities are decoded.
+*/
+type XattrRecord struct {
+	Path         string            `json:"path"`
+	Inode        uint64            `json:"inode"`
+	Xattrs       []XattrValue      `json:"xattrs"`
+	ACL          []string          `json:"acl,omitempty"`
+	DefaultACL   []string          `json:"default_acl,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Capabilities string            `json:"capabilities,omitempty"`
+}
+
+// XattrValue is a single extended attribute. Binary values are base64 encoded.
+type XattrValue struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Encoding  string `json:"encoding"`
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Security labels by the attribute holding them.
+var labelAttributes = map[string]string{
+	"security.selinux":     "selinux",
+	"security.apparmor":    "apparmor",
+	"security.SMACK64":     "smack",
+	"security.SMACK64EXEC": "smack_exec",
+}
+
+/*
+Add records an extended attribute and decodes it when it is an ACL, a security label or file
+capabilities.
+*/
+func (r *XattrRecord) Add(name string, value []byte) {
+
+	x := XattrValue{Name: name, Size: len(value)}
+
+	shown := value
+	if len(shown) > XattrValueLimit {
+		shown = shown[:XattrValueLimit]
+		x.Truncated = true
+	}
+
+	if isText(shown) {
+		x.Value, x.Encoding = string(shown), "text"
+	} else {
+		x.Value, x.Encoding = base64.StdEncoding.EncodeToString(shown), "base64"
+	}
+
+	r.Xattrs = append(r.Xattrs, x)
+
+	switch name {
+	case "system.posix_acl_access":
+		r.ACL, _ = DecodeACL(value)
+	case "system.posix_acl_default":
+		r.DefaultACL, _ = DecodeACL(value)
+	case "security.capability":
+		r.Capabilities, _ = DecodeCapabilities(value)
+	}
+
+	if label, ok := labelAttributes[name]; ok {
+		if r.Labels == nil {
+			r.Labels = make(map[string]string)
+		}
+		r.Labels[label] = strings.TrimRight(string(value), "\x00")
+	}
+}
+
+/*
+Names returns the names of the extended attributes.
+*/
+func (r *XattrRecord) Names() []string {
+
+	names := make([]string, len(r.Xattrs))
+	for i, x := range r.Xattrs {
+		names[i] = x.Name
+	}
+
+	return names
+}
+
+/*
+Checks if the value can be stored as text. Labels end with a NUL, which is allowed.
+*/
+func isText(value []byte) bool {
+
+	value = []byte(strings.TrimSuffix(string(value), "\x00"))
+	if !utf8.Valid(value) {
+		return false
+	}
+
+	for _, r := range string(value) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// POSIX ACL entry tags, see linux/posix_acl_xattr.h.
+const (
+	aclUserObj  = 0x01
+	aclUser     = 0x02
+	aclGroupObj = 0x04
+	aclGroup    = 0x08
+	aclMask     = 0x10
+	aclOther    = 0x20
+)
+
+/*
+DecodeACL decodes a system.posix_acl_access or system.posix_acl_default value into entries
+written the way getfacl does (user:1000:r-x).
+*/
+func DecodeACL(value []byte) ([]string, error) {
+
+	if len(value) < 4 || binary.LittleEndian.Uint32(value) != 2 {
+		return nil, fmt.Errorf("unknown ACL version")
+	}
+
+	var entries []string
+
+	for b := value[4:]; len(b) >= 8; b = b[8:] {
+
+		tag := binary.LittleEndian.Uint16(b)
+		perm := binary.LittleEndian.Uint16(b[2:])
+		id := strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b[4:])), 10)
+
+		perms := []byte("---")
+		for i, bit := range []uint16{4, 2, 1} {
+			if perm&bit != 0 {
+				perms[i] = "rwx"[i]
+			}
+		}
+
+		var entry string
+		switch tag {
+		case aclUserObj:
+			entry = "user::"
+		case aclUser:
+			entry = "user:" + id + ":"
+		case aclGroupObj:
+			entry = "group::"
+		case aclGroup:
+			entry = "group:" + id + ":"
+		case aclMask:
+			entry = "mask::"
+		case aclOther:
+			entry = "other::"
+		default:
+			entry = fmt.Sprintf("tag%d:%s:", tag, id)
+		}
+
+		entries = append(entries, entry+string(perms))
+	}
+
+	return entries, nil
+}
+
+// Capability names by number, see linux/capability.h.
+var capabilityNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "setgid", "setuid",
+	"setpcap", "linux_immutable", "net_bind_service", "net_broadcast", "net_admin", "net_raw",
+	"ipc_lock", "ipc_owner", "sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time", "sys_tty_config", "mknod",
+	"lease", "audit_write", "audit_control", "setfcap", "mac_override", "mac_admin", "syslog",
+	"wake_alarm", "block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+// File capability revisions, see linux/capability.h.
+const (
+	capRevisionMask = 0xFF000000
+	capRevision1    = 0x01000000
+	capRevision2    = 0x02000000
+	capRevision3    = 0x03000000
+	capEffective    = 0x000001
+)
+
+/*
+DecodeCapabilities decodes a security.capability value the way getcap shows it
+(cap_net_admin,cap_net_raw=ep).
+*/
+func DecodeCapabilities(value []byte) (string, error) {
+
+	if len(value) < 4 {
+		return "", fmt.Errorf("capabilities too short")
+	}
+
+	magic := binary.LittleEndian.Uint32(value)
+
+	// Each set is a permitted and an inheritable 32 bit mask.
+	sets := 2
+	switch magic & capRevisionMask {
+	case capRevision1:
+		sets = 1
+	case capRevision2, capRevision3:
+	default:
+		return "", fmt.Errorf("unknown capabilities revision %#x", magic&capRevisionMask)
+	}
+
+	if len(value) < 4+sets*8 {
+		return "", fmt.Errorf("capabilities too short")
+	}
+
+	var permitted, inheritable uint64
+	for i := 0; i < sets; i++ {
+		permitted |= uint64(binary.LittleEndian.Uint32(value[4+i*8:])) << (32 * i)
+		inheritable |= uint64(binary.LittleEndian.Uint32(value[8+i*8:])) << (32 * i)
+	}
+
+	// Capabilities with the same flags are listed together.
+	var order []string
+	groups := make(map[string][]string)
+
+	for bit := 0; bit < 64; bit++ {
+
+		p, i := permitted&(1<<bit) != 0, inheritable&(1<<bit) != 0
+		if !p && !i {
+			continue
+		}
+
+		flags := ""
+		if magic&capEffective != 0 {
+			flags += "e"
+		}
+		if i {
+			flags += "i"
+		}
+		if p {
+			flags += "p"
+		}
+
+		name := fmt.Sprintf("cap_%d", bit)
+		if bit < len(capabilityNames) {
+			name = "cap_" + capabilityNames[bit]
+		}
+
+		if _, ok := groups[flags]; !ok {
+			order = append(order, flags)
+		}
+		groups[flags] = append(groups[flags], name)
+	}
+
+	var parts []string
+	for _, flags := range order {
+		parts = append(parts, strings.Join(groups[flags], ",")+"="+flags)
+	}
+
+	// Namespaced capabilities only apply to the user namespace owned by the root ID.
+	if magic&capRevisionMask == capRevision3 && len(value) >= 24 {
+		parts = append(parts, fmt.Sprintf("[rootid=%d]", binary.LittleEndian.Uint32(value[20:])))
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+/*
+ReadXattrs reads an xattrs sidecar and returns the records by path. The last record of a path
+wins, which is the latest one when a collection was resumed.
+*/
+func ReadXattrs(r io.Reader) (map[string]*XattrRecord, error) {
+
+	records := make(map[string]*XattrRecord)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record XattrRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		records[record.Path] = &record
+	}
+
+	return records, scanner.Err()
+}
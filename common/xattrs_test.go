@@ -0,0 +1,72 @@
+package common
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestDecodeACL(t *testing.T) {
+	value := binary.LittleEndian.AppendUint32(nil, 2)
+	for _, e := range [][3]uint32{{aclUserObj, 6, 0xffffffff}, {aclUser, 7, 1000}, {aclGroupObj, 4, 0xffffffff}, {aclMask, 5, 0xffffffff}, {aclOther, 0, 0xffffffff}} {
+		value = binary.LittleEndian.AppendUint16(value, uint16(e[0]))
+		value = binary.LittleEndian.AppendUint16(value, uint16(e[1]))
+		value = binary.LittleEndian.AppendUint32(value, e[2])
+	}
+
+	got, err := DecodeACL(value)
+	want := "user::rw-,user:1000:rwx,group::r--,mask::r-x,other::---"
+	if err != nil || strings.Join(got, ",") != want {
+		t.Errorf("DecodeACL() = %v, %v, want %s", got, err, want)
+	}
+
+	if _, err := DecodeACL([]byte{1, 0, 0, 0}); err == nil {
+		t.Error("DecodeACL() accepted an unknown version")
+	}
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	tests := []struct {
+		magic       uint32
+		permitted   uint64
+		inheritable uint64
+		want        string
+	}{
+		{capRevision2 | capEffective, 1<<12 | 1<<13, 0, "cap_net_admin,cap_net_raw=ep"},
+		{capRevision2, 1 << 21, 1 << 21, "cap_sys_admin=ip"},
+		{capRevision2 | capEffective, 1 << 40, 1 << 0, "cap_chown=ei cap_checkpoint_restore=ep"},
+	}
+
+	for _, tt := range tests {
+		value := binary.LittleEndian.AppendUint32(nil, tt.magic)
+		for i := 0; i < 2; i++ {
+			value = binary.LittleEndian.AppendUint32(value, uint32(tt.permitted>>(32*i)))
+			value = binary.LittleEndian.AppendUint32(value, uint32(tt.inheritable>>(32*i)))
+		}
+
+		if got, err := DecodeCapabilities(value); err != nil || got != tt.want {
+			t.Errorf("DecodeCapabilities(%#x, %#x, %#x) = %q, %v, want %q", tt.magic, tt.permitted, tt.inheritable, got, err, tt.want)
+		}
+	}
+
+	if _, err := DecodeCapabilities([]byte{0, 0, 0, 9}); err == nil {
+		t.Error("DecodeCapabilities() accepted an unknown revision")
+	}
+}
+
+func TestXattrRecordAdd(t *testing.T) {
+	var r XattrRecord
+	r.Add("user.note", []byte("hello"))
+	r.Add("user.blob", make([]byte, XattrValueLimit+10))
+	r.Add("security.selinux", []byte("system_u:object_r:bin_t:s0\x00"))
+
+	if x := r.Xattrs[0]; x.Encoding != "text" || x.Value != "hello" {
+		t.Errorf("text value = %+v", x)
+	}
+	if x := r.Xattrs[1]; x.Encoding != "base64" || !x.Truncated || x.Size != XattrValueLimit+10 {
+		t.Errorf("binary value = %+v", x)
+	}
+	if r.Labels["selinux"] != "system_u:object_r:bin_t:s0" {
+		t.Errorf("selinux label = %q", r.Labels["selinux"])
+	}
+}
@@ -0,0 +1,445 @@
+package createBody
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"gobodyfile/common"
+)
+
+// ErrInterrupted is returned by CreateBody when the collection was stopped with Ctrl-C or SIGTERM.
+var ErrInterrupted = errors.New("collection interrupted")
+
+// Number of paths collected between checkpoints.
+const checkpointInterval = 1000
+
+// Ctrl-C or SIGTERM received since the collection started.
+var interrupt chan os.Signal
+
+/*
+checkpoint records the progress of a collection so it can be resumed with -resume.
+*/
+type checkpoint struct {
+	Directory string    `json:"directory"`
+	Options   Options   `json:"options"`
+	Collected int       `json:"collected"`
+	Last      string    `json:"last"`
+	Updated   time.Time `json:"updated"`
+}
+
+/*
+Returns the name of the checkpoint file of a body file.
+*/
+func checkpointFile(outputFile string) string {
+	return outputFile + ".checkpoint"
+}
+
+/*
+collector tracks the paths collected so far, writes checkpoints and watches for interrupts.
+*/
+type collector struct {
+	outputFile string
+	cp         checkpoint
+	done       map[string]bool
+	interrupt  chan os.Signal
+	progress   *progress
+	out        *bodyWriter
+}
+
+/*
+Starts a collection shown by the progress of the walk. With -resume, the checkpoint is checked
+against the current options and the paths already in the body file are skipped. Otherwise, a new
+checkpoint is written.
+*/
+func startCollection(rootDir string, outputFile string, p *progress) (*collector, error) {
+
+	c := &collector{outputFile: outputFile, done: make(map[string]bool), progress: p}
+
+	// The checkpoint is compared without -resume and how the output was opened.
+	opts := options
+	opts.Resume, opts.Force, opts.Append = false, false, false
+	c.cp = checkpoint{Directory: rootDir, Options: opts}
+
+	if options.Resume {
+
+		// Compressed, encrypted and packaged output can't be checked or appended to.
+		if !plainOutput(outputFile) {
+			return nil, fmt.Errorf("-resume only works with uncompressed and unencrypted output written outside a package")
+		}
+
+		data, err := os.ReadFile(checkpointFile(outputFile))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no checkpoint found for %s, the collection already finished or never started", outputFile)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var saved checkpoint
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint: %v", err)
+		}
+
+		if saved.Directory != rootDir || !reflect.DeepEqual(saved.Options, opts) {
+			return nil, fmt.Errorf("the checkpoint was written for -directory %s with different options, use the same options to resume", saved.Directory)
+		}
+
+		if err := c.loadCollected(); err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", outputFile, err)
+		}
+
+		c.cp.Collected = len(c.done)
+		fmt.Printf("Resuming after %d entries, last collected %s\n", c.cp.Collected, saved.Last)
+	}
+
+	// A new collection replaces the output and starts with the header.
+	create := !options.Resume && !options.Append
+
+	out, err := openBodyOutput(outputFile, create)
+	if err != nil {
+		return nil, err
+	}
+	c.out = out
+
+	if err := openXattrs(outputFile, create); err != nil {
+		c.out.Close()
+		closePackage()
+		return nil, fmt.Errorf("could not create %s: %v", xattrsFile(outputFile), err)
+	}
+
+	if err := c.save(); err != nil {
+		c.out.Close()
+		closeXattrs(outputFile)
+		return nil, fmt.Errorf("could not write the checkpoint: %v", err)
+	}
+
+	c.interrupt = interrupt
+
+	return c, nil
+}
+
+/*
+Starts listening for Ctrl-C and SIGTERM. It is done before walking the directories, the longest
+part on large trees, so an interrupted walk still leaves a checkpoint.
+*/
+func notifyInterrupt() {
+
+	interrupt = make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+}
+
+/*
+Stops listening for Ctrl-C and SIGTERM.
+*/
+func stopInterrupt() {
+	signal.Stop(interrupt)
+}
+
+/*
+Checks for Ctrl-C or SIGTERM while walking the directories. The signal is left for the collector,
+which writes the checkpoint.
+*/
+func interruptPending() bool {
+	return len(interrupt) > 0
+}
+
+/*
+Reads the paths already written to the body file. A line left half written when the collection
+died is removed so it is collected again.
+*/
+func (c *collector) loadCollected() error {
+
+	f, err := os.OpenFile(c.outputFile, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+
+	for {
+
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			if line != "" {
+				return f.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+
+		e, deleted, err := parseOutputLine(strings.TrimRight(line, "\r\n"))
+		if err != nil || e == nil {
+			continue
+		}
+
+		// Keep the tombstones and the unchanged entries of -since-body correct.
+		if previous != nil {
+			seen[e.key()] = true
+			if !deleted {
+				previous[e.key()] = e
+			}
+		}
+
+		if !deleted {
+			c.done[e.name] = true
+		}
+	}
+}
+
+/*
+Parses a body file or JSON Lines entry written by a collection. Returns nil for other comment lines.
+*/
+func parseOutputLine(line string) (*entry, bool, error) {
+
+	switch {
+	case line == "":
+		return nil, false, nil
+
+	case strings.HasPrefix(line, "{"):
+		var j entryJSON
+		if err := json.Unmarshal([]byte(line), &j); err != nil {
+			return nil, false, err
+		}
+		return &entry{j.MD5, j.Name, j.Inode, j.Mode, j.UID, j.GID, j.Size, j.Atime, j.Mtime, j.Ctime, j.Crtime, j.Type, string(j.Entropy), j.SSDeep}, j.Deleted, nil
+
+	case strings.HasPrefix(line, tombstonePrefix):
+		e, err := parseBodyLine(strings.TrimPrefix(line, tombstonePrefix), columnNames())
+		return e, true, err
+
+	case strings.HasPrefix(line, "#"):
+		return nil, false, nil
+	}
+
+	e, err := parseBodyLine(line, columnNames())
+
+	return e, false, err
+}
+
+/*
+Opens the output of a collection. A new body file starts with the header, one being appended to
+must be plain and have the same extended columns.
+*/
+func openBodyOutput(outputFile string, create bool) (*bodyWriter, error) {
+
+	// Appending needs the same plain output as resuming.
+	if options.Append && !plainOutput(outputFile) {
+		return nil, fmt.Errorf("-append only works with uncompressed and unencrypted output written outside a package")
+	}
+	if options.Append {
+		if err := checkAppendColumns(outputFile); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := openOutput(outputFile, create)
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s: %v", outputFile, err)
+	}
+
+	if create {
+		if err := writeHeader(out); err != nil {
+			out.abort()
+			return nil, fmt.Errorf("could not write to %s: %v", outputFile, err)
+		}
+	}
+
+	return out, nil
+}
+
+/*
+Checks that the body file being appended to declares the extended columns of this collection, so
+its lines all have the same fields.
+*/
+func checkAppendColumns(outputFile string) error {
+
+	f, err := os.Open(outputFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err != nil || info.Size() == 0 {
+		return err
+	}
+
+	header, _, err := common.ReadHeader(f)
+	if err != nil {
+		return err
+	}
+
+	var columns []string
+	if header != nil {
+		columns = header.Columns
+	}
+
+	if strings.Join(columns, ",") != strings.Join(columnNames(), ",") {
+		return fmt.Errorf("%s has the extended columns [%s], use the same options to append to it", outputFile, strings.Join(columns, ","))
+	}
+
+	return nil
+}
+
+/*
+Writes the checkpoint. A temporary file is renamed over it so it is never left half written.
+*/
+func (c *collector) save() error {
+
+	// A package is finished when the collection stops, so there is nothing to resume.
+	if options.Package != "" {
+		return nil
+	}
+
+	c.cp.Updated = time.Now()
+
+	data, err := json.MarshalIndent(c.cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := checkpointFile(c.outputFile) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, checkpointFile(c.outputFile))
+}
+
+/*
+Checks if the path was collected before the collection was resumed.
+*/
+func (c *collector) skip(path string) bool {
+
+	if !c.done[entryName(path)] {
+		return false
+	}
+
+	stats.Skipped++
+	c.progress.advance(path)
+
+	return true
+}
+
+/*
+Records a collected path and writes a checkpoint every checkpointInterval paths.
+*/
+func (c *collector) collected(path string, dir bool) {
+
+	c.cp.Collected++
+	c.cp.Last = path
+
+	if dir {
+		stats.Directories++
+	} else {
+		stats.Files++
+	}
+	c.progress.advance(path)
+
+	if c.cp.Collected%checkpointInterval == 0 {
+
+		// The checkpoint can't be ahead of the body file.
+		if err := c.out.Flush(); err != nil {
+			logError(c.outputFile, opWrite, fmt.Errorf("failed to write to output file: %w", err))
+		}
+
+		if err := c.save(); err != nil {
+			logError(c.outputFile, opWrite, fmt.Errorf("failed to write checkpoint: %w", err))
+		}
+	}
+}
+
+/*
+Checks for Ctrl-C or SIGTERM. When received, the checkpoint is written so the collection can be resumed.
+*/
+func (c *collector) stopped() bool {
+
+	select {
+	case <-c.interrupt:
+	default:
+		return false
+	}
+
+	signal.Stop(c.interrupt)
+	c.progress.done()
+	c.close()
+
+	if err := c.save(); err != nil {
+		fmt.Printf("Could not write the checkpoint: %v\n", err)
+	}
+
+	c.complete(true)
+
+	if options.Package != "" {
+		fmt.Printf("Interrupted after %d entries. The package holds the entries collected so far.\n", c.cp.Collected)
+	} else {
+		fmt.Printf("Interrupted after %d entries. Run the same command with -resume to continue.\n", c.cp.Collected)
+	}
+
+	return true
+}
+
+/*
+Closes the output so the compression and encryption are finished.
+*/
+func (c *collector) close() {
+
+	if err := c.out.Close(); err != nil {
+		logError(c.outputFile, opWrite, fmt.Errorf("failed to close output file: %w", err))
+	}
+}
+
+/*
+Removes the checkpoint and writes the summary and manifest once the collection is complete.
+*/
+func (c *collector) finish() {
+
+	signal.Stop(c.interrupt)
+	c.progress.done()
+	c.close()
+
+	if err := os.Remove(checkpointFile(c.outputFile)); err != nil && !os.IsNotExist(err) {
+		logError(c.outputFile, opWrite, fmt.Errorf("failed to remove checkpoint: %w", err))
+	}
+
+	c.complete(false)
+}
+
+/*
+Writes the sidecars, summary and manifest after the body file. Writing the sidecars can still log
+errors, so the error log is closed after them and the manifest is written last to hash the final
+files. With -package the package is closed after the manifest.
+*/
+func (c *collector) complete(interrupted bool) {
+
+	writeOwners(c.outputFile)
+	closeXattrs(c.outputFile)
+	writeSummary(c.outputFile, interrupted)
+
+	// Errors logged from here on aren't in the error log, only counted in the manifest.
+	addErrorLog(c.outputFile)
+	closeErrorLog()
+
+	if err := writeManifest(c.outputFile); err != nil {
+		fmt.Printf("Could not write the manifest: %v\n", err)
+	}
+
+	if err := closePackage(); err != nil {
+		fmt.Printf("Could not write the package: %v\n", err)
+	}
+}
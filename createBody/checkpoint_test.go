@@ -0,0 +1,110 @@
+package createBody
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	collectedLine = "0|/srv/a|1|33188|0|0|5|100|200|300|0"
+	tornLine      = "0|/srv/b|2|33188|0|0|5|1"
+)
+
+/*
+Writes a body file and the checkpoint of an interrupted collection of dir.
+*/
+func writeInterrupted(t *testing.T, dir string, body string, saved Options) string {
+
+	outputFile := filepath.Join(t.TempDir(), "out.body")
+	os.WriteFile(outputFile, []byte(body), 0644)
+
+	data, _ := json.Marshal(checkpoint{Directory: dir, Options: saved, Collected: 1, Last: "/srv/a"})
+	os.WriteFile(checkpointFile(outputFile), data, 0644)
+
+	return outputFile
+}
+
+func TestResumeTruncatesTornLine(t *testing.T) {
+	defer func() { options = Options{} }()
+
+	outputFile := writeInterrupted(t, "/srv", collectedLine+"\n"+tornLine, Options{})
+	options = Options{Resume: true}
+
+	c, err := startCollection("/srv", outputFile, newProgress())
+	if err != nil {
+		t.Fatalf("startCollection() error: %v", err)
+	}
+	c.out.Close()
+
+	if data, _ := os.ReadFile(outputFile); string(data) != collectedLine+"\n" {
+		t.Errorf("body file after resuming = %q, want the torn line removed", data)
+	}
+	if !c.skip("/srv/a") || c.skip("/srv/b") {
+		t.Errorf("collected paths = %v, want only /srv/a skipped", c.done)
+	}
+	if c.cp.Collected != 1 {
+		t.Errorf("checkpoint collected = %d, want 1", c.cp.Collected)
+	}
+}
+
+func TestResumeRefusesMismatch(t *testing.T) {
+	defer func() { options = Options{} }()
+
+	tests := []struct {
+		name  string
+		dir   string
+		saved Options
+	}{
+		{"directory", "/home", Options{}},
+		{"options", "/srv", Options{MD5: true}},
+	}
+
+	for _, tt := range tests {
+		outputFile := writeInterrupted(t, tt.dir, collectedLine+"\n", tt.saved)
+		options = Options{Resume: true}
+
+		if c, err := startCollection("/srv", outputFile, newProgress()); err == nil {
+			c.out.Close()
+			t.Errorf("startCollection() with a different %s resumed", tt.name)
+		}
+	}
+
+	// A finished collection has no checkpoint left.
+	outputFile := writeInterrupted(t, "/srv", collectedLine+"\n", Options{})
+	os.Remove(checkpointFile(outputFile))
+	if _, err := startCollection("/srv", outputFile, newProgress()); err == nil {
+		t.Error("startCollection() resumed without a checkpoint")
+	}
+}
+
+func TestResumeSinceBody(t *testing.T) {
+	defer func() { options, previous, seen = Options{}, nil, nil }()
+
+	// The previous body file had /srv/a and /srv/c, the interrupted run changed /srv/a and deleted /srv/c.
+	old, _ := parseBodyLine("0|/srv/a|1|33188|0|0|4|100|100|100|0", nil)
+	gone, _ := parseBodyLine("0|/srv/c|3|33188|0|0|1|100|100|100|0", nil)
+	previous = map[string]*entry{old.key(): old, gone.key(): gone}
+	seen = make(map[string]bool)
+
+	body := collectedLine + "\n" + tombstonePrefix + gone.line() + "\n" + tornLine
+	outputFile := writeInterrupted(t, "/srv", body, Options{SinceBody: "monday.body"})
+	options = Options{Resume: true, SinceBody: "monday.body"}
+
+	c, err := startCollection("/srv", outputFile, newProgress())
+	if err != nil {
+		t.Fatalf("startCollection() error: %v", err)
+	}
+	c.out.Close()
+
+	if !seen[old.key()] || !seen[gone.key()] {
+		t.Errorf("seen = %v, want /srv/a and the tombstone of /srv/c", seen)
+	}
+	if previous[old.key()].line() != collectedLine {
+		t.Errorf("previous /srv/a = %q, want the entry written before the interruption", previous[old.key()].line())
+	}
+	if c.done["/srv/c"] {
+		t.Error("the tombstone of /srv/c was taken as a collected path")
+	}
+}
@@ -0,0 +1,128 @@
+package createBody
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/glaslos/ssdeep"
+
+	"gobodyfile/common"
+)
+
+// Files of this size or smaller have no ssdeep hash, the library refuses them as too small to say
+// anything about their similarity.
+const fuzzyMinSize = 4096
+
+/*
+Reads the content of a regular file for the MD5, the type, the entropy and the ssdeep hash the
+options ask for. The file is opened once and read in a single pass, the type comes from its first
+block. Only the start and the entropy samples are read when nothing needs the whole file. The MD5
+and ssdeep hash from the previous body file are reused when the size, modification and change times
+haven't changed.
+*/
+func readContent(path string, e *entry, regular bool) {
+
+	e.md5 = "0"
+	if !regular {
+		return
+	}
+
+	withinMax := options.HashMaxSize <= 0 || e.size <= options.HashMaxSize
+	prev, unchanged := previous[e.key()]
+	unchanged = unchanged && prev.size == e.size && prev.mtime == e.mtime && prev.ctime == e.ctime
+
+	hashMD5 := options.MD5 && withinMax
+	if hashMD5 && unchanged && prev.md5 != "0" {
+		e.md5, hashMD5 = prev.md5, false
+	}
+
+	fuzzy := options.SSDeep && withinMax && e.size > fuzzyMinSize
+	if fuzzy && unchanged && prev.ssdeep != "" {
+		e.ssdeep, fuzzy = prev.ssdeep, false
+	}
+
+	whole := hashMD5 || fuzzy || (options.Entropy && e.size <= entropyFullSize)
+	if !whole && !options.Types && !options.Entropy {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		logError(path, opOpen, fmt.Errorf("failed to open file to read its content: %w", err))
+		return
+	}
+	defer f.Close()
+
+	md5Hash := md5.New()
+	fuzzyHash := ssdeep.New()
+	counter := &entropyCounter{size: e.size}
+
+	var writers []io.Writer
+	if hashMD5 {
+		writers = append(writers, md5Hash)
+	}
+	if fuzzy {
+		writers = append(writers, fuzzyHash)
+	}
+	if options.Entropy && whole {
+		writers = append(writers, counter)
+	}
+	w := io.MultiWriter(writers...)
+
+	var read int64
+	if options.Types || whole {
+		head := make([]byte, common.SniffSize)
+		n, err := io.ReadFull(f, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			logError(path, opRead, fmt.Errorf("failed to read file: %w", err))
+			return
+		}
+		read = int64(n)
+
+		if options.Types {
+			e.fileType = common.DetectType(head[:n])
+		}
+		w.Write(head[:n])
+	}
+
+	if whole {
+		n, err := io.Copy(w, f)
+		read += n
+		if hashMD5 {
+			stats.BytesHashed += read
+		}
+		if err != nil {
+			logError(path, opRead, fmt.Errorf("failed to read file: %w", err))
+			return
+		}
+	} else if options.Entropy {
+		if err := counter.sample(f); err != nil {
+			logError(path, opRead, fmt.Errorf("failed to read file for its entropy: %w", err))
+			return
+		}
+	}
+
+	if hashMD5 {
+		e.md5 = hex.EncodeToString(md5Hash.Sum(nil))
+	}
+
+	if fuzzy {
+		// The hash is empty when the file shrank or grew out of what ssdeep accepts.
+		e.ssdeep = string(fuzzyHash.Sum(nil))
+		if e.ssdeep == "" {
+			err := ssdeep.ErrFileTooBig
+			if read <= fuzzyMinSize {
+				err = ssdeep.ErrFileTooSmall
+			}
+			logError(path, opHash, fmt.Errorf("failed to fuzzy hash file: %w", err))
+		}
+	}
+
+	if options.Entropy {
+		e.entropy = strconv.FormatFloat(shannonEntropy(counter.counts), 'f', 2, 64)
+	}
+}
@@ -0,0 +1,68 @@
+package createBody
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glaslos/ssdeep"
+)
+
+func TestReadContent(t *testing.T) {
+	defer func() { options, previous, stats = Options{}, nil, Summary{} }()
+
+	data := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog.\n", 300))
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, data, 0644)
+
+	sum := md5.Sum(data)
+	wantMD5 := hex.EncodeToString(sum[:])
+	wantSSDeep, _ := ssdeep.FuzzyBytes(data)
+	size := int64(len(data))
+
+	// The previous collection saw the same file, unchanged.
+	prev := &entry{name: path, size: size, mtime: 100, ctime: 200, md5: "0123456789abcdef0123456789abcdef", ssdeep: "3:prev:prev"}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		previous map[string]*entry
+		md5      string
+		fileType string
+		ssdeep   string
+		hashed   int64
+	}{
+		{"all", Options{MD5: true, Types: true, Entropy: true, SSDeep: true}, nil, wantMD5, "text", wantSSDeep, size},
+		{"none", Options{}, nil, "0", "", "", 0},
+		{"type only", Options{Types: true}, nil, "0", "text", "", 0},
+		{"too big to hash", Options{MD5: true, Types: true, SSDeep: true, HashMaxSize: 100}, nil, "0", "text", "", 0},
+		{"reused", Options{MD5: true, SSDeep: true}, map[string]*entry{prev.key(): prev}, prev.md5, "", prev.ssdeep, 0},
+	}
+
+	for _, tt := range tests {
+		options, previous, stats = tt.opts, tt.previous, Summary{}
+
+		e := &entry{name: path, size: size, mtime: 100, ctime: 200}
+		readContent(path, e, true)
+
+		if e.md5 != tt.md5 || e.fileType != tt.fileType || e.ssdeep != tt.ssdeep {
+			t.Errorf("readContent(%s) = md5 %q, type %q, ssdeep %q, want %q, %q, %q", tt.name, e.md5, e.fileType, e.ssdeep, tt.md5, tt.fileType, tt.ssdeep)
+		}
+		if (e.entropy != "") != tt.opts.Entropy {
+			t.Errorf("readContent(%s) entropy = %q with -entropy %v", tt.name, e.entropy, tt.opts.Entropy)
+		}
+		if stats.BytesHashed != tt.hashed || stats.Errors != 0 {
+			t.Errorf("readContent(%s) hashed %d bytes with %d errors, want %d bytes", tt.name, stats.BytesHashed, stats.Errors, tt.hashed)
+		}
+	}
+
+	// Nothing left to read, so a file that is gone isn't opened.
+	options, previous, stats = Options{MD5: true, SSDeep: true}, map[string]*entry{prev.key(): prev}, Summary{}
+	e := &entry{name: path, size: size, mtime: 100, ctime: 200}
+	if readContent(filepath.Join(t.TempDir(), "gone"), e, true); e.md5 != prev.md5 || stats.Errors != 0 {
+		t.Errorf("readContent() of reused hashes = md5 %q with %d errors, want the previous MD5 without opening the file", e.md5, stats.Errors)
+	}
+}
@@ -5,64 +5,30 @@ package createBody
 import (
 	"fmt"
 	"gobodyfile/common"
+	"io"
 	"os"
 	"path/filepath"
 	"syscall"
 	"time"
 )
 
-// Error log file handle.
-var errorLog *os.File
-
-/*
-Initialize error logging.
-*/
-func initErrorLog(outputFile string) error {
-	errorLogPath := outputFile + ".errors.log"
-	var err error
-	errorLog, err = os.OpenFile(errorLogPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to create error log file: %v", err)
-	}
-	return nil
-}
-
-/*
-Close error log file.
-*/
-func closeErrorLog() {
-	if errorLog != nil {
-		errorLog.Close()
-	}
-}
-
-/*
-Log error to error log file.
-*/
-func logError(filename string, err error) {
-	if errorLog != nil {
-		timestamp := time.Now().Format("2006-01-02 15:04:05")
-		errorLog.WriteString(fmt.Sprintf("[%s] %s: %v\n", timestamp, filename, err))
-	}
-}
-
 /*
 statFDir is used to get the file system information.
 */
-func statFDir(toStat string, output string) error {
+func statFDir(toStat string, w io.Writer) error {
 
 	// Lstat is used to not follow symlinks and to get data on the symlink.
 	theFile, err := os.Lstat(toStat)
 
 	if err != nil {
-		logError(toStat, fmt.Errorf("failed to stat file: %v", err))
+		logError(toStat, opLstat, fmt.Errorf("failed to stat file: %w", err))
 		return err
 	}
 
 	// Get the file's inode data.
 	stat, ok := theFile.Sys().(*syscall.Stat_t)
 	if !ok {
-		logError(toStat, fmt.Errorf("failed to get file system info"))
+		logError(toStat, opLstat, fmt.Errorf("failed to get file system info"))
 		return fmt.Errorf("failed to get file system info")
 	}
 
@@ -95,27 +61,43 @@ func statFDir(toStat string, output string) error {
 	ctime := time.Unix(stat.Ctimespec.Sec, stat.Ctimespec.Nsec)
 	crtime := ctime
 
-	// Create the file and open it.
-	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-
-	if err != nil {
-		logError(toStat, fmt.Errorf("failed to open output file: %v", err))
-		return err
+	e := &entry{
+		name:   entryName(toStat),
+		inode:  inode,
+		mode:   fmt.Sprintf("%d", mode),
+		uid:    fmt.Sprintf("%d", uid),
+		gid:    fmt.Sprintf("%d", gid),
+		size:   fsize,
+		atime:  atime.Unix(),
+		mtime:  mtime.Unix(),
+		ctime:  ctime.Unix(),
+		crtime: crtime.Unix(),
 	}
 
-	defer file.Close()
+	// Get the file's MD5, type, entropy and ssdeep hash if enabled, reading it once.
+	readContent(toStat, e, mode.IsRegular())
 
-	// Write the results to a file.
-	_, err = fmt.Fprintf(file, "0|%s|%d|%d|%d|%d|%d|%d|%d|%d|%d\n", toStat, inode, mode, uid, gid, fsize, atime.Unix(), mtime.Unix(), ctime.Unix(), crtime.Unix())
+	// Write the results to the output.
+	err = writeEntry(w, e)
 	if err != nil {
-		logError(toStat, fmt.Errorf("failed to write to output file: %v", err))
+		logError(toStat, opWrite, fmt.Errorf("failed to write to output file: %w", err))
 		return err
 	}
 
 	return nil
 }
 
-func CreateBody(rootDir string, outputFile string, sid bool) {
+/*
+CreateBody writes the body file of the directories, whose globs are expanded, and the paths listed
+in -paths-from. Returns ErrInterrupted when stopped with Ctrl-C or SIGTERM, leaving a checkpoint for
+-resume.
+*/
+func CreateBody(dirs []string, outputFile string, opts Options) error {
+
+	options = opts
+	directories = dirs
+	rootDir := rootsLabel(false)
+	resetStats(rootDir, outputFile)
 
 	// Initialize error logging.
 	if err := initErrorLog(outputFile); err != nil {
@@ -123,22 +105,55 @@ func CreateBody(rootDir string, outputFile string, sid bool) {
 	}
 	defer closeErrorLog()
 
+	// Load the previous body file to only write what changed since then.
+	if options.SinceBody != "" {
+		if err := loadPreviousBody(options.SinceBody); err != nil {
+			fmt.Printf("Could not read the previous body file: %v\n", err)
+			return err
+		}
+	}
+
 	var fileList, dirList []string
 
-	// Check if the output file exists.
-	common.CheckFileExists(outputFile)
+	// Check if the output file exists. A resumed collection continues into it.
+	if !options.Resume {
+		if err := common.CheckOutputFile(collectionFile(outputFile), options.Force, options.Append); err != nil {
+			fmt.Println(err)
+			return err
+		}
+	}
+
+	// Stop on Ctrl-C or SIGTERM from here on, the walk is the longest part on large trees.
+	notifyInterrupt()
+	defer stopInterrupt()
+
+	// Expand the directories and read the listed paths.
+	roots, err := collectionRoots(dirs)
+	if err != nil {
+
+		fmt.Printf("Unable to start the collection: %v\n", err)
+		return err
+
+	}
 
-	// Check if the directory exists.
-	common.CheckDirectoryExists(rootDir)
+	// Show the paths found while walking, which is the longest part on large trees.
+	p := newProgress()
 
-	// Crawl through the directory.
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+	// Crawl through the directories.
+	err = walkRoots(roots, func(path string, info os.FileInfo, err error) error {
 
 		if err != nil {
-			logError(path, fmt.Errorf("failed to access path: %v", err))
+			logError(path, opWalk, fmt.Errorf("failed to access path: %w", err))
 			return nil
 		}
 
+		// Stop walking when interrupted, the collector writes the checkpoint before returning.
+		if interruptPending() {
+			return filepath.SkipAll
+		}
+
+		p.found(path)
+
 		// Add directories to the directory slice.
 		if info.IsDir() {
 
@@ -157,28 +172,84 @@ func CreateBody(rootDir string, outputFile string, sid bool) {
 	if err != nil {
 
 		fmt.Printf("Unable to crawl through the directory: %s\n", err)
-		return
+		return err
+
+	}
+
+	// Record the progress so an interrupted collection can be resumed. The walk counted the paths
+	// for the ETA.
+	c, err := startCollection(rootDir, outputFile, p)
+	if err != nil {
+
+		fmt.Printf("Unable to start the collection: %v\n", err)
+		return err
+
+	}
 
+	// Interrupted while walking, nothing was collected yet.
+	if c.stopped() {
+		return ErrInterrupted
 	}
 
 	// Loop through each file and print the results.
 	for _, file := range fileList {
 
-		if err := statFDir(file, outputFile); err != nil {
+		if c.stopped() {
+			return ErrInterrupted
+		}
+
+		if c.skip(file) {
+			continue
+		}
+
+		if err := statFDir(file, c.out); err != nil {
 			// Error already logged in statFDir, just continue
 			continue
 		}
 
+		c.collected(file, false)
+
 	}
 
 	// Loop through each directory and print the results.
 	for _, dir := range dirList {
 
-		if err := statFDir(dir, outputFile); err != nil {
+		if c.stopped() {
+			return ErrInterrupted
+		}
+
+		if c.skip(dir) {
+			continue
+		}
+
+		if err := statFDir(dir, c.out); err != nil {
 			// Error already logged in statFDir, just continue.
 			continue
 		}
 
+		c.collected(dir, true)
+
+	}
+
+	// Record the entries that were deleted since the previous body file.
+	if err := writeTombstones(c.out); err != nil {
+		logError(outputFile, opWrite, fmt.Errorf("failed to write tombstones: %w", err))
+	}
+
+	c.finish()
+
+	return strictResult()
+}
+
+/*
+Returns the kernel release.
+*/
+func kernelVersion() string {
+
+	release, err := syscall.Sysctl("kern.osrelease")
+	if err != nil {
+		return ""
 	}
 
+	return release
 }
@@ -22,48 +22,16 @@ The 2.X output has the following fields:
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"golang.org/x/sys/windows"
-)
-
-// Error log file handle.
-var errorLog *os.File
 
-/*
-Initialize error logging.
-*/
-func initErrorLog(outputFile string) error {
-	errorLogPath := outputFile + ".errors.log"
-	var err error
-	errorLog, err = os.OpenFile(errorLogPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to create error log file: %v", err)
-	}
-	return nil
-}
-
-/*
-Close error log file.
-*/
-func closeErrorLog() {
-	if errorLog != nil {
-		errorLog.Close()
-	}
-}
-
-/*
-Log error to error log file.
-*/
-func logError(filename string, err error) {
-	if errorLog != nil {
-		timestamp := time.Now().Format("2006-01-02 15:04:05")
-		errorLog.WriteString(fmt.Sprintf("[%s] %s: %v\n", timestamp, filename, err))
-	}
-}
+	"gobodyfile/common"
+)
 
 /*
 Returns the files time to the UNIX Epoch.
@@ -164,12 +132,12 @@ func modeOctal(perm os.FileMode) string {
 /*
 Returns the file the metadata for each file.
 */
-func processFile(filename string, outputFile *os.File, theSID bool) error {
+func processFile(filename string, w io.Writer, theSID bool) error {
 
 	// Fetch SIDs
 	uidSID, groupSID, err := getSIDs(filename, theSID)
 	if err != nil {
-		logError(filename, fmt.Errorf("failed to get SIDs: %v", err))
+		logError(filename, opSID, fmt.Errorf("failed to get SIDs: %w", err))
 		return err
 	}
 
@@ -185,14 +153,14 @@ func processFile(filename string, outputFile *os.File, theSID bool) error {
 	// Fetch file's information using os package.
 	fileInfo, err := os.Stat(filename)
 	if err != nil {
-		logError(filename, fmt.Errorf("failed to stat file: %v", err))
+		logError(filename, opLstat, fmt.Errorf("failed to stat file: %w", err))
 		return err
 	}
 
 	// Opens the file with no special privileges, don't lock the file (*FILE_SHARE*), and open the file even it is already open.
 	getFileInfo, err := windows.CreateFile(&windows.StringToUTF16(filename)[0], 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
 	if err != nil {
-		logError(filename, fmt.Errorf("failed to open file: %v", err))
+		logError(filename, opOpen, fmt.Errorf("failed to open file: %w", err))
 		return err
 	}
 
@@ -201,7 +169,7 @@ func processFile(filename string, outputFile *os.File, theSID bool) error {
 	// Gets the files last access time, last write time, and creation time.
 	var theFile windows.ByHandleFileInformation
 	if err := windows.GetFileInformationByHandle(getFileInfo, &theFile); err != nil {
-		logError(filename, fmt.Errorf("failed to get file information: %v", err))
+		logError(filename, opLstat, fmt.Errorf("failed to get file information: %w", err))
 		return err
 	}
 
@@ -226,17 +194,43 @@ func processFile(filename string, outputFile *os.File, theSID bool) error {
 	// Creation time.
 	crtime := filetimeToTime(theFile.CreationTime).Unix()
 
+	e := &entry{
+		name:   entryName(filename),
+		inode:  inode,
+		mode:   fmode,
+		uid:    uidSID,
+		gid:    groupSID,
+		size:   size,
+		atime:  atime,
+		mtime:  mtime,
+		ctime:  ctime,
+		crtime: crtime,
+	}
+
+	// Get the file's MD5, type, entropy and ssdeep hash if enabled, reading it once.
+	readContent(filename, e, fileInfo.Mode().IsRegular())
+
 	// Write the body file format to the output file.
-	_, err = outputFile.WriteString(fmt.Sprintf("0|%s|%d|%s|%s|%s|%d|%d|%d|%d|%d\n", filename, inode, fmode, uidSID, groupSID, size, atime, mtime, ctime, crtime))
+	err = writeEntry(w, e)
 	if err != nil {
-		logError(filename, fmt.Errorf("failed to write to output file: %v", err))
+		logError(filename, opWrite, fmt.Errorf("failed to write to output file: %w", err))
 		return err
 	}
 
 	return nil
 }
 
-func CreateBody(rootDir string, outputFile string, theSID bool) {
+/*
+CreateBody writes the body file of the directories, whose globs are expanded, and the paths listed
+in -paths-from. Returns ErrInterrupted when stopped with Ctrl-C or SIGTERM, leaving a checkpoint for
+-resume.
+*/
+func CreateBody(dirs []string, outputFile string, opts Options) error {
+
+	options = opts
+	directories = dirs
+	rootDir := rootsLabel(false)
+	resetStats(rootDir, outputFile)
 
 	// Initialize error logging.
 	if err := initErrorLog(outputFile); err != nil {
@@ -244,65 +238,83 @@ func CreateBody(rootDir string, outputFile string, theSID bool) {
 	}
 	defer closeErrorLog()
 
-	// If s is not set to true, set it to false.
-	if !theSID {
-		theSID = false
-	}
+	theSID := options.SID
 
-	// Check that the directory exists.
-	if _, err := os.Stat(rootDir); err != nil {
-		fmt.Println("The directory does not exist.")
-		os.Exit(1)
+	// Load the previous body file to only write what changed since then.
+	if options.SinceBody != "" {
+		if err := loadPreviousBody(options.SinceBody); err != nil {
+			fmt.Printf("Could not read the previous body file: %v\n", err)
+			return err
+		}
 	}
 
-	// Check that the output file doesn't exist.
-	if _, err := os.Stat(outputFile); err == nil {
-		fmt.Println("The output file already exists. Do you want to delete it? (y/n)")
-
-		var answer string
-		fmt.Scanln(&answer)
-
-		// If so, then prompt y or n to delete it.
-		if answer == "y" {
-
-			os.Remove(outputFile)
-
-			// Otherwise, exit.
-		} else {
+	// Check that the output file doesn't exist. A resumed collection continues into it.
+	if !options.Resume {
+		if err := common.CheckOutputFile(collectionFile(outputFile), options.Force, options.Append); err != nil {
+			fmt.Println(err)
+			return err
+		}
+	}
 
-			fmt.Println("The output file was not deleted.")
+	// Stop on Ctrl-C or SIGTERM from here on, the walk is the longest part on large trees.
+	notifyInterrupt()
+	defer stopInterrupt()
 
-			os.Exit(1)
+	// Expand the directories and read the listed paths.
+	roots, err := collectionRoots(dirs)
+	if err != nil {
+		fmt.Printf("Unable to start the collection: %v\n", err)
+		return err
+	}
 
-		}
+	// Files are collected while walking, so the paths are only counted for an ETA with -count.
+	p := newProgress()
+	if options.Count {
+		walkRoots(roots, func(path string, info os.FileInfo, err error) error {
+			if interruptPending() {
+				return filepath.SkipAll
+			}
+			if err == nil && !info.IsDir() {
+				p.found(path)
+			}
+			return nil
+		})
+	}
 
+	// Record the progress so an interrupted collection can be resumed.
+	c, err := startCollection(rootDir, outputFile, p)
+	if err != nil {
+		fmt.Printf("Unable to start the collection: %v\n", err)
+		return err
 	}
 
-	// Walk through the directory and each of its subdirectories.
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+	interrupted := false
+
+	// Walk through the directories and each of their subdirectories.
+	err = walkRoots(roots, func(path string, info os.FileInfo, err error) error {
 		// If there's an error accessing the directory/file, log it and continue.
 		if err != nil {
-			logError(path, fmt.Errorf("failed to access path: %v", err))
+			logError(path, opWalk, fmt.Errorf("failed to access path: %w", err))
 			return nil
 		}
 
-		// Only process files.
-		if !info.IsDir() {
+		// Stop walking when interrupted.
+		if c.stopped() {
+			interrupted = true
+			return filepath.SkipAll
+		}
 
-			// Open the output file in append mode.
-			file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-			if err != nil {
-				logError(path, fmt.Errorf("failed to open output file: %v", err))
-				return nil
-			}
-			defer file.Close()
+		// Only process files that weren't collected before resuming.
+		if !info.IsDir() && !c.skip(path) {
 
 			// Get the file's metadata.
-			if err := processFile(path, file, theSID); err != nil {
+			if err := processFile(path, c.out, theSID); err != nil {
 				// Error already logged in processFile, just continue.
 				return nil
 			}
 
+			c.collected(path, false)
+
 		}
 
 		return nil
@@ -313,4 +325,26 @@ func CreateBody(rootDir string, outputFile string, theSID bool) {
 		fmt.Printf("Error during directory walk: %v\n", err)
 	}
 
+	if interrupted {
+		return ErrInterrupted
+	}
+
+	// Record the entries that were deleted since the previous body file.
+	if err := writeTombstones(c.out); err != nil {
+		logError(outputFile, opWrite, fmt.Errorf("failed to write tombstones: %w", err))
+	}
+
+	c.finish()
+
+	return strictResult()
+}
+
+/*
+Returns the Windows version and build number.
+*/
+func kernelVersion() string {
+
+	v := windows.RtlGetVersion()
+
+	return fmt.Sprintf("%d.%d.%d", v.MajorVersion, v.MinorVersion, v.BuildNumber)
 }
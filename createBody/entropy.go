@@ -0,0 +1,101 @@
+package createBody
+
+import (
+	"io"
+	"math"
+)
+
+// Files up to this size are read whole to compute their entropy. Bigger files are sampled.
+const entropyFullSize = 1 << 20
+
+// Blocks read from a bigger file, spread evenly from its start to its end.
+const (
+	entropySamples    = 16
+	entropySampleSize = 64 << 10
+)
+
+/*
+entropyCounter counts the bytes of a file written to it from the start: all of them up to
+entropyFullSize, only those in the sampled blocks of a bigger file.
+*/
+type entropyCounter struct {
+	counts [256]int64
+	size   int64
+	offset int64
+}
+
+/*
+Returns the distance between the starts of the sampled blocks.
+*/
+func (c *entropyCounter) step() int64 {
+	return (c.size - entropySampleSize) / (entropySamples - 1)
+}
+
+func (c *entropyCounter) Write(p []byte) (int, error) {
+
+	n := len(p)
+
+	for len(p) > 0 {
+		count := len(p)
+
+		if c.size > entropyFullSize {
+			// Skip to the block the offset is in, or the next one.
+			i := c.offset / c.step()
+			if i >= entropySamples {
+				break
+			}
+			start := i * c.step()
+			if c.offset >= start+entropySampleSize {
+				skip := min(int64(len(p)), (i+1)*c.step()-c.offset)
+				c.offset += skip
+				p = p[skip:]
+				continue
+			}
+			count = int(min(int64(count), start+entropySampleSize-c.offset))
+		}
+
+		for _, b := range p[:count] {
+			c.counts[b]++
+		}
+		c.offset += int64(count)
+		p = p[count:]
+	}
+
+	return n, nil
+}
+
+/*
+Reads only the sampled blocks of a file bigger than entropyFullSize.
+*/
+func (c *entropyCounter) sample(f io.ReaderAt) error {
+
+	for i := int64(0); i < entropySamples; i++ {
+		c.offset = i * c.step()
+		if _, err := io.Copy(c, io.NewSectionReader(f, c.offset, entropySampleSize)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Returns the Shannon entropy in bits per byte of the byte counts.
+*/
+func shannonEntropy(counts [256]int64) float64 {
+
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+
+	entropy := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+
+	return entropy
+}
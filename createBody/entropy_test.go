@@ -0,0 +1,77 @@
+package createBody
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileEntropy(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options = Options{Entropy: true}
+
+	dir := t.TempDir()
+
+	every := make([]byte, 256)
+	for i := range every {
+		every[i] = byte(i)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"zeros", make([]byte, 4096), "0.00"},
+		{"two", bytes.Repeat([]byte("ab"), 100), "1.00"},
+		{"every", every, "8.00"},
+		// Bigger than entropyFullSize, so only samples are read.
+		{"sampled", bytes.Repeat(every, 3*entropyFullSize/256), "8.00"},
+		{"empty", nil, "0.00"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		e := &entry{size: int64(len(tt.data))}
+		if readContent(path, e, true); e.entropy != tt.want {
+			t.Errorf("readContent(%s) entropy = %q, want %q", tt.name, e.entropy, tt.want)
+		}
+	}
+
+	e := &entry{}
+	if readContent(dir, e, false); e.entropy != "" {
+		t.Errorf("readContent() of a directory entropy = %q, want none", e.entropy)
+	}
+}
+
+func TestEntropySamples(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+
+	// Each sampled block has its own byte value, the bytes between them are zeros.
+	size := int64(3*entropyFullSize + 12345)
+	data := make([]byte, size)
+	c := &entropyCounter{size: size}
+	for i := int64(0); i < entropySamples; i++ {
+		start := i * c.step()
+		for j := start; j < start+entropySampleSize; j++ {
+			data[j] = byte(i + 1)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "big")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the whole file for the MD5 counts the same blocks as reading only the samples.
+	for _, opts := range []Options{{Entropy: true}, {Entropy: true, MD5: true}} {
+		options = opts
+		e := &entry{size: size}
+		if readContent(path, e, true); e.entropy != "4.00" {
+			t.Errorf("readContent() with MD5 %v entropy = %q, want 4.00", opts.MD5, e.entropy)
+		}
+	}
+}
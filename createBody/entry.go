@@ -0,0 +1,402 @@
+package createBody
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gobodyfile/common"
+)
+
+/*
+Options for creating the body file.
+*/
+type Options struct {
+
+	// Display the SID instead of the UID and GID (Windows only).
+	SID bool
+
+	// Compute the MD5 of regular files.
+	MD5 bool
+
+	// Previous body file. Only new or changed entries are written along with tombstones for deleted ones.
+	SinceBody string
+
+	// Write JSON Lines instead of body file lines.
+	JSON bool
+
+	// Continue an interrupted collection into the same body file.
+	Resume bool
+
+	// Count the paths before collecting to show an ETA on Windows, which collects while walking.
+	Count bool
+
+	// Write the error log as JSON Lines.
+	ErrorJSON bool
+
+	// Fail the collection when critical errors are logged.
+	Strict bool
+
+	// Compression of the output (gzip, zstd or none). Picked from the extension when empty.
+	Compress string
+
+	// age recipients and a file of recipients to encrypt the output to.
+	Recipients     []string
+	RecipientsFile string
+
+	// age identity file to read an encrypted -since-body.
+	Identity string
+
+	// Zip archive the body file, error log, summary and manifest are written to.
+	Package string
+
+	// Write the extended attributes, ACLs and security labels to the xattrs sidecar (Linux only).
+	Xattrs bool
+
+	// Write the account and group names to the owners sidecar, read from the passwd and group
+	// files under OwnersRoot ("/" when empty).
+	Owners     bool
+	OwnersRoot string
+
+	// Rewrite the names to what they were on the original system: StripPrefix is removed and
+	// MountPrefix put in its place. StripPrefix defaults to the directory with a MountPrefix.
+	MountPrefix string
+	StripPrefix string
+
+	// Write backslashes in names as slashes.
+	NormalizePaths bool
+
+	// File listing more paths to collect, one per line or NUL separated. "-" reads stdin.
+	PathsFrom string
+
+	// Collection profile the directories and the settings below came from.
+	Profile string
+
+	// Patterns of the paths to leave out, and of the files to collect. A pattern with a slash
+	// matches the whole path, otherwise the name.
+	Exclude []string
+	Include []string
+
+	// Directories deeper than this under a root aren't walked. 0 walks everything.
+	MaxDepth int
+
+	// Files bigger than this aren't hashed. 0 hashes every regular file.
+	HashMaxSize int64
+
+	// Detect the type of regular files from their first bytes, written in the type column.
+	Types bool
+
+	// Compute the entropy of regular files, written in the entropy column.
+	Entropy bool
+
+	// Compute the ssdeep fuzzy hash of regular files, written in the ssdeep column.
+	SSDeep bool
+
+	// Replace an existing output, or add the entries to it without a new header.
+	Force  bool
+	Append bool
+}
+
+// Options for the current collection.
+var options Options
+
+/*
+entry holds the metadata of a file in the body file format. The mode, UID and GID are strings
+since Windows records the mode in octal and can record SIDs.
+*/
+type entry struct {
+	md5    string
+	name   string
+	inode  uint64
+	mode   string
+	uid    string
+	gid    string
+	size   int64
+	atime  int64
+	mtime  int64
+	ctime  int64
+	crtime int64
+
+	// Extended columns, empty when they weren't collected.
+	fileType string
+	entropy  string
+	ssdeep   string
+}
+
+// Prefix of the comment lines recording entries deleted since the previous body file.
+const tombstonePrefix = "#deleted|"
+
+/*
+Returns the key identifying the entry across collections.
+*/
+func (e *entry) key() string {
+	return e.name + "|" + strconv.FormatUint(e.inode, 10)
+}
+
+/*
+Returns the entry as a body file line, with the extended columns of the collection after the crtime.
+*/
+func (e *entry) line() string {
+
+	line := fmt.Sprintf("%s|%s|%d|%s|%s|%s|%d|%d|%d|%d|%d", e.md5, e.name, e.inode, e.mode, e.uid, e.gid, e.size, e.atime, e.mtime, e.ctime, e.crtime)
+	for _, column := range columnNames() {
+		line += "|" + *e.column(column)
+	}
+
+	return line
+}
+
+// Extended columns an entry can have, in the order they are written.
+var knownColumns = []string{"type", "entropy", "ssdeep"}
+
+/*
+Returns the extended columns written by the collection, in order. They are declared in the header
+so readers can remove them before reading the 11 fields of the TSK format.
+*/
+func columnNames() []string {
+
+	var columns []string
+	if options.Types {
+		columns = append(columns, "type")
+	}
+	if options.Entropy {
+		columns = append(columns, "entropy")
+	}
+	if options.SSDeep {
+		columns = append(columns, "ssdeep")
+	}
+
+	return columns
+}
+
+/*
+Returns the value of an extended column, or nil for an unknown column.
+*/
+func (e *entry) column(name string) *string {
+
+	switch name {
+	case "type":
+		return &e.fileType
+	case "entropy":
+		return &e.entropy
+	case "ssdeep":
+		return &e.ssdeep
+	}
+
+	return nil
+}
+
+/*
+entryJSON is the JSON Lines form of an entry.
+*/
+type entryJSON struct {
+	MD5     string      `json:"md5"`
+	Name    string      `json:"name"`
+	Inode   uint64      `json:"inode"`
+	Mode    string      `json:"mode"`
+	UID     string      `json:"uid"`
+	GID     string      `json:"gid"`
+	Size    int64       `json:"size"`
+	Atime   int64       `json:"atime"`
+	Mtime   int64       `json:"mtime"`
+	Ctime   int64       `json:"ctime"`
+	Crtime  int64       `json:"crtime"`
+	Type    string      `json:"type,omitempty"`
+	Entropy json.Number `json:"entropy,omitempty"`
+	SSDeep  string      `json:"ssdeep,omitempty"`
+	Deleted bool        `json:"deleted,omitempty"`
+}
+
+/*
+Returns the entry as a JSON line.
+*/
+func (e *entry) json(deleted bool) string {
+
+	data, _ := json.Marshal(entryJSON{e.md5, e.name, e.inode, e.mode, e.uid, e.gid, e.size, e.atime, e.mtime, e.ctime, e.crtime, e.fileType, json.Number(e.entropy), e.ssdeep, deleted})
+
+	return string(data)
+}
+
+/*
+Parses a body file line with the extended columns from its header. Names containing a pipe are
+kept whole.
+*/
+func parseBodyLine(line string, columns []string) (*entry, error) {
+
+	fields := strings.Split(line, "|")
+	if len(fields) < 11+len(columns) {
+		return nil, fmt.Errorf("expected %d fields, got %d", 11+len(columns), len(fields))
+	}
+
+	// The name is everything between the MD5 and the last nine fields before the columns.
+	n := len(fields) - len(columns)
+	e := &entry{
+		md5:  fields[0],
+		name: strings.Join(fields[1:n-9], "|"),
+		mode: fields[n-8],
+		uid:  fields[n-7],
+		gid:  fields[n-6],
+	}
+
+	var err error
+	numbers := []*int64{&e.size, &e.atime, &e.mtime, &e.ctime, &e.crtime}
+	for i, field := range fields[n-5 : n] {
+		if *numbers[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q: %v", field, err)
+		}
+	}
+
+	if e.inode, err = strconv.ParseUint(fields[n-9], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid inode %q: %v", fields[n-9], err)
+	}
+
+	// Columns this version doesn't know about are dropped.
+	for i, column := range columns {
+		if value := e.column(column); value != nil {
+			*value = fields[n+i]
+		}
+	}
+
+	return e, nil
+}
+
+// Entries of the previous body file keyed by path and inode.
+var previous map[string]*entry
+
+// Keys of the previous entries that still exist.
+var seen map[string]bool
+
+/*
+Loads the previous body file used by -since-body.
+*/
+func loadPreviousBody(bodyFile string) error {
+
+	f, err := common.OpenBody(bodyFile, options.Identity)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	previous = make(map[string]*entry)
+	seen = make(map[string]bool)
+
+	// The previous collection can have other extended columns.
+	header, r, err := common.ReadHeader(f)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	if header != nil {
+		columns = header.Columns
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e, err := parseBodyLine(line, columns)
+		if err != nil {
+			logError(bodyFile, opRead, fmt.Errorf("failed to parse previous body line: %w", err))
+			continue
+		}
+
+		previous[e.key()] = e
+	}
+
+	return scanner.Err()
+}
+
+/*
+Writes the entry to the body file. With -since-body, entries identical to the previous body file are
+skipped. The access time isn't compared, reading a file to hash it updates it.
+*/
+func writeEntry(w io.Writer, e *entry) error {
+
+	if previous != nil {
+		prev, ok := previous[e.key()]
+		if ok {
+			seen[e.key()] = true
+
+			// Only compare the hashes and extended columns when both collections have them.
+			same := *prev
+			same.atime = e.atime
+			if prev.md5 == "0" || e.md5 == "0" {
+				same.md5 = e.md5
+			}
+			for _, column := range knownColumns {
+				if *prev.column(column) == "" || *e.column(column) == "" {
+					*same.column(column) = *e.column(column)
+				}
+			}
+			if same == *e {
+				return nil
+			}
+		}
+	}
+
+	var err error
+	if options.JSON {
+		_, err = fmt.Fprintln(w, e.json(false))
+	} else {
+		_, err = fmt.Fprintln(w, e.line())
+	}
+
+	// Remember what was written so repeated watch events for an unchanged file are skipped.
+	if previous != nil && err == nil {
+		previous[e.key()] = e
+		seen[e.key()] = true
+	}
+
+	return err
+}
+
+/*
+Writes a tombstone for a deleted entry.
+*/
+func writeTombstone(w io.Writer, e *entry) error {
+
+	var err error
+	if options.JSON {
+		_, err = fmt.Fprintln(w, e.json(true))
+	} else {
+		_, err = fmt.Fprintln(w, tombstonePrefix+e.line())
+	}
+
+	return err
+}
+
+/*
+Writes a tombstone for each entry of the previous body file that no longer exists.
+*/
+func writeTombstones(w io.Writer) error {
+
+	if previous == nil {
+		return nil
+	}
+
+	var deleted []string
+	for key := range previous {
+		if !seen[key] {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+
+	for _, key := range deleted {
+		if err := writeTombstone(w, previous[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
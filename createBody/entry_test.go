@@ -0,0 +1,79 @@
+package createBody
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseBodyLine(t *testing.T) {
+	lines := []string{
+		"0|/etc/passwd|1234|420|0|0|2048|1750536031|1750536031|1750536031|1750536031",
+		"d41d8cd98f00b204e9800998ecf8427e|/tmp/odd|name|99|0644|S-1-5-18|S-1-5-32-544|0|1|2|3|4",
+	}
+	for _, line := range lines {
+		e, err := parseBodyLine(line, nil)
+		if err != nil {
+			t.Fatalf("parseBodyLine(%q) returned error: %v", line, err)
+		}
+		if e.line() != line {
+			t.Errorf("parseBodyLine(%q).line() = %q", line, e.line())
+		}
+	}
+
+	if _, err := parseBodyLine("0|/etc/passwd|1234", nil); err == nil {
+		t.Errorf("parseBodyLine should fail on a short line")
+	}
+}
+
+func TestParseBodyLineColumns(t *testing.T) {
+	defer func(saved Options) { options = saved }(options)
+	options = Options{Types: true}
+
+	line := "0|/tmp/odd|invoice.pdf|99|33188|0|0|0|1|2|3|4|elf"
+	e, err := parseBodyLine(line, []string{"type"})
+	if err != nil {
+		t.Fatalf("parseBodyLine(%q) returned error: %v", line, err)
+	}
+	if e.name != "/tmp/odd|invoice.pdf" || e.fileType != "elf" || e.line() != line {
+		t.Errorf("parseBodyLine(%q) = %+v, line() = %q", line, e, e.line())
+	}
+
+	// Unknown columns of a newer collection are dropped.
+	e, err = parseBodyLine("0|/tmp/a|99|33188|0|0|0|1|2|3|4|elf|7.9", []string{"type", "unknown"})
+	if err != nil || e.name != "/tmp/a" || e.fileType != "elf" {
+		t.Errorf("parseBodyLine() with an unknown column = %+v, %v", e, err)
+	}
+}
+
+func TestWriteEntrySinceBody(t *testing.T) {
+	defer func() { previous, seen = nil, nil }()
+
+	prev, _ := parseBodyLine("d41d8cd98f00b204e9800998ecf8427e|/srv/a|12|33188|0|0|5|100|200|300|0", nil)
+	previous = map[string]*entry{prev.key(): prev}
+	seen = make(map[string]bool)
+
+	tests := []struct {
+		name    string
+		line    string
+		written bool
+	}{
+		{"atime", "d41d8cd98f00b204e9800998ecf8427e|/srv/a|12|33188|0|0|5|999|200|300|0", false},
+		{"md5", "0cc175b9c0f1b6a831c399e269772661|/srv/a|12|33188|0|0|5|100|200|300|0", true},
+		{"size", "d41d8cd98f00b204e9800998ecf8427e|/srv/a|12|33188|0|0|6|100|200|300|0", true},
+		{"mtime", "d41d8cd98f00b204e9800998ecf8427e|/srv/a|12|33188|0|0|5|100|201|300|0", true},
+		{"mode", "d41d8cd98f00b204e9800998ecf8427e|/srv/a|12|33261|0|0|5|100|200|300|0", true},
+	}
+
+	for _, tt := range tests {
+		previous[prev.key()] = prev
+
+		e, _ := parseBodyLine(tt.line, nil)
+		var out bytes.Buffer
+		if err := writeEntry(&out, e); err != nil {
+			t.Fatalf("writeEntry(%s) error: %v", tt.name, err)
+		}
+		if written := out.Len() > 0; written != tt.written {
+			t.Errorf("writeEntry() with a different %s wrote %q, want written %v", tt.name, out.String(), tt.written)
+		}
+	}
+}
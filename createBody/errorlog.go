@@ -0,0 +1,208 @@
+package createBody
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrStrict is returned by CreateBody in -strict mode when critical errors were logged.
+var ErrStrict = errors.New("critical errors during collection")
+
+// Operations that failed.
+const (
+	opWalk  = "walk"
+	opLstat = "lstat"
+	opOpen  = "open"
+	opHash  = "hash"
+	opWrite = "write"
+	opSID   = "sid"
+	opRead  = "read"
+	opWatch = "watch"
+	opXattr = "xattr"
+)
+
+// Categories of errors.
+const (
+	categoryPermission = "permission"
+	categoryVanished   = "vanished"
+	categoryIO         = "io"
+	categoryNoSpace    = "no_space"
+	categoryTooMany    = "too_many_files"
+	categoryOther      = "other"
+)
+
+// Error log file handle, or the in-memory log with -package.
+var errorLog io.Writer
+
+/*
+errorRecord is a line of the JSON Lines error log.
+*/
+type errorRecord struct {
+	Time      time.Time `json:"time"`
+	Path      string    `json:"path"`
+	Operation string    `json:"op"`
+	Category  string    `json:"category"`
+	Errno     int       `json:"errno,omitempty"`
+	Error     string    `json:"error"`
+}
+
+/*
+Returns the name of the error log of a body file.
+*/
+func errorLogFile(outputFile string) string {
+
+	if options.ErrorJSON {
+		return outputFile + ".errors.jsonl"
+	}
+
+	return outputFile + ".errors.log"
+}
+
+/*
+Initialize error logging.
+*/
+func initErrorLog(outputFile string) error {
+
+	// The package is written one file at a time, so the error log is added once the body is done.
+	if options.Package != "" {
+		errorBuffer = new(bytes.Buffer)
+		errorLog = errorBuffer
+		return nil
+	}
+
+	f, err := os.OpenFile(errorLogFile(outputFile), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create error log file: %v", err)
+	}
+	errorLog = f
+	return nil
+}
+
+/*
+Close error log file.
+*/
+func closeErrorLog() {
+	if f, ok := errorLog.(*os.File); ok {
+		f.Close()
+	}
+	errorLog = nil
+}
+
+/*
+Returns the category of an error and its errno when there is one.
+*/
+func errorCategory(err error) (string, int) {
+
+	errno := 0
+	var e syscall.Errno
+	if errors.As(err, &e) {
+		errno = int(e)
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return categoryPermission, errno
+	case errors.Is(err, fs.ErrNotExist):
+		return categoryVanished, errno
+	case errors.Is(err, syscall.EIO):
+		return categoryIO, errno
+	case errors.Is(err, syscall.ENOSPC):
+		return categoryNoSpace, errno
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE):
+		return categoryTooMany, errno
+	}
+
+	return categoryOther, errno
+}
+
+/*
+Checks if an error means the body file is missing entries it should have. Files that vanished
+during the collection aren't critical.
+*/
+func critical(op string, category string) bool {
+
+	switch category {
+	case categoryPermission, categoryIO, categoryNoSpace:
+		return true
+	}
+
+	return op == opWrite
+}
+
+/*
+Log error to error log file. op is the operation that failed.
+*/
+func logError(filename string, op string, err error) {
+
+	category, errno := errorCategory(err)
+
+	stats.Errors++
+	if stats.ErrorCategories == nil {
+		stats.ErrorCategories = make(map[string]int)
+	}
+	stats.ErrorCategories[category]++
+	if critical(op, category) {
+		stats.CriticalErrors++
+	}
+
+	if errorLog == nil {
+		return
+	}
+
+	if options.ErrorJSON {
+		data, _ := json.Marshal(errorRecord{time.Now(), filename, op, category, errno, err.Error()})
+		errorLog.Write(append(data, '\n'))
+		return
+	}
+
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	fmt.Fprintf(errorLog, "[%s] %s: %v\n", timestamp, filename, err)
+}
+
+/*
+Returns the per-category counts as "category: count" sorted by category.
+*/
+func formatCategories(categories map[string]int) string {
+
+	var names []string
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var counts []string
+	for _, name := range names {
+		counts = append(counts, fmt.Sprintf("%s: %d", name, categories[name]))
+	}
+
+	return strings.Join(counts, ", ")
+}
+
+/*
+Returns ErrStrict in -strict mode when critical errors were logged.
+*/
+func strictResult() error {
+
+	if !options.Strict || stats.CriticalErrors == 0 {
+		return nil
+	}
+
+	log := errorLogFile(stats.Output)
+	if options.Package != "" {
+		log = fmt.Sprintf("%s in %s", filepath.Base(log), options.Package)
+	}
+
+	fmt.Printf("Strict mode: %d critical errors (%s), see %s\n", stats.CriticalErrors, formatCategories(stats.ErrorCategories), log)
+
+	return ErrStrict
+}
@@ -0,0 +1,31 @@
+package createBody
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		err      error
+		op       string
+		category string
+		critical bool
+	}{
+		{fmt.Errorf("failed to stat file: %w", &fs.PathError{Op: "lstat", Path: "/x", Err: syscall.EACCES}), opLstat, categoryPermission, true},
+		{fmt.Errorf("failed to access path: %w", &fs.PathError{Op: "lstat", Path: "/x", Err: syscall.ENOENT}), opWalk, categoryVanished, false},
+		{fmt.Errorf("failed to hash file: %w", syscall.EIO), opHash, categoryIO, true},
+		{fmt.Errorf("short write"), opWrite, categoryOther, true},
+	}
+	for _, test := range tests {
+		category, _ := errorCategory(test.err)
+		if category != test.category {
+			t.Errorf("errorCategory(%v) = %q, want %q", test.err, category, test.category)
+		}
+		if critical(test.op, category) != test.critical {
+			t.Errorf("critical(%q, %q) = %v, want %v", test.op, category, !test.critical, test.critical)
+		}
+	}
+}
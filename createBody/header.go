@@ -0,0 +1,62 @@
+package createBody
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"gobodyfile/common"
+)
+
+/*
+Returns the header describing the current collection.
+*/
+func collectionHeader() *common.Header {
+
+	h := &common.Header{
+		Tool:      fmt.Sprintf("gobodyfile %s (%s/%s)", common.Version, runtime.GOOS, runtime.GOARCH),
+		Root:      rootsLabel(true),
+		Collected: time.Now().UTC(),
+		Timezone:  common.LocalTimezone(),
+		Precision: "seconds",
+		Since:     options.SinceBody,
+		Profile:   options.Profile,
+		Scope:     collectionScope(),
+		Columns:   columnNames(),
+	}
+
+	h.Host, _ = os.Hostname()
+
+	if options.MD5 {
+		h.Extensions = append(h.Extensions, "md5")
+	}
+	if options.SID {
+		h.Extensions = append(h.Extensions, "sid")
+	}
+	if options.SinceBody != "" {
+		h.Extensions = append(h.Extensions, "tombstones")
+	}
+	if collectXattrs() {
+		h.Extensions = append(h.Extensions, "xattrs")
+	}
+	if options.Owners {
+		h.Extensions = append(h.Extensions, "owners")
+	}
+
+	return h
+}
+
+/*
+Writes the header at the start of a new body file. JSON Lines output has no header since it can't
+have comment lines.
+*/
+func writeHeader(w io.Writer) error {
+
+	if options.JSON {
+		return nil
+	}
+
+	return collectionHeader().Write(w)
+}
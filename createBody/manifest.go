@@ -0,0 +1,320 @@
+package createBody
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gobodyfile/common"
+)
+
+// ErrManifestMismatch is returned by VerifyManifest when a file is missing or its hash changed.
+var ErrManifestMismatch = errors.New("manifest verification failed")
+
+/*
+Manifest records how a body file was produced so it can be shown in a report and checked later.
+*/
+type Manifest struct {
+	Tool        ManifestTool   `json:"tool"`
+	Host        ManifestHost   `json:"host"`
+	CommandLine []string       `json:"command_line"`
+	Timezone    string         `json:"timezone"`
+	Root        string         `json:"root"`
+	Summary     Summary        `json:"summary"`
+	Files       []ManifestFile `json:"files"`
+}
+
+// ManifestTool is the build of gobodyfile that made the collection.
+type ManifestTool struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	GoVersion    string `json:"go_version"`
+	Binary       string `json:"binary"`
+	BinarySHA256 string `json:"binary_sha256"`
+}
+
+// ManifestHost is the system the collection ran on.
+type ManifestHost struct {
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Kernel   string `json:"kernel"`
+	User     string `json:"user"`
+	UID      string `json:"uid"`
+}
+
+// ManifestFile is an output file and its hash. The name is relative to the manifest.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+/*
+Returns the name of the manifest of a body file.
+*/
+func manifestFile(outputFile string) string {
+	return outputFile + ".manifest.json"
+}
+
+/*
+Returns the SHA-256 and size of a file.
+*/
+func sha256File(path string) (string, int64, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	return sha256Reader(f)
+}
+
+/*
+Returns the SHA-256 and size of what is read from r.
+*/
+func sha256Reader(r io.Reader) (string, int64, error) {
+
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+/*
+Returns the SHA-256 of a file listed in a manifest. Snapshots compressed by -serve-snapshots
+after the manifest was written are hashed decompressed.
+*/
+func sha256Listed(path string) (string, error) {
+
+	sum, _, err := sha256File(path)
+	if !os.IsNotExist(err) {
+		return sum, err
+	}
+
+	f, gzErr := os.Open(path + ".gz")
+	if gzErr != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	zr, gzErr := gzip.NewReader(f)
+	if gzErr != nil {
+		return "", gzErr
+	}
+
+	sum, _, err = sha256Reader(zr)
+
+	return sum, err
+}
+
+/*
+Returns the user the collection runs as. On Unix this is the effective user, which is what
+decides the files that can be read.
+*/
+func effectiveUser() (string, string) {
+
+	if euid := os.Geteuid(); euid >= 0 {
+		uid := strconv.Itoa(euid)
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username, uid
+		}
+		return "", uid
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username, u.Uid
+	}
+
+	return "", ""
+}
+
+/*
+Writes the manifest of the collection next to the output, or into the package. It is written last
+so it covers the final body file and error log.
+*/
+func writeManifest(outputFile string) error {
+
+	m := Manifest{
+		Tool: ManifestTool{
+			Name:      "gobodyfile",
+			Version:   common.Version,
+			GoVersion: runtime.Version(),
+		},
+		Host: ManifestHost{
+			OS:     runtime.GOOS,
+			Arch:   runtime.GOARCH,
+			Kernel: kernelVersion(),
+		},
+		CommandLine: os.Args,
+		Summary:     stats,
+		Root:        rootsLabel(true),
+	}
+
+	if binary, err := os.Executable(); err == nil {
+		m.Tool.Binary = binary
+		m.Tool.BinarySHA256, _, _ = sha256File(binary)
+	}
+
+	m.Host.Hostname, _ = os.Hostname()
+	m.Host.User, m.Host.UID = effectiveUser()
+
+	m.Timezone = stats.Started.Format("MST -07:00")
+
+	// The files in a package were hashed while they were written.
+	if evidence != nil {
+		evidence.finishEntry()
+		m.Files = append(m.Files, evidence.files...)
+	} else {
+		paths := []string{outputFile, errorLogFile(outputFile)}
+		if collectXattrs() {
+			paths = append(paths, xattrsFile(outputFile))
+		}
+
+		// The owners sidecar is missing when the accounts couldn't be read.
+		if _, err := os.Stat(ownersFile(outputFile)); err == nil && options.Owners {
+			paths = append(paths, ownersFile(outputFile))
+		}
+
+		for _, path := range paths {
+
+			sum, size, err := sha256File(path)
+			if err != nil {
+				return err
+			}
+
+			m.Files = append(m.Files, ManifestFile{Name: filepath.Base(path), Size: size, SHA256: sum})
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeSideFile(manifestFile(outputFile), append(data, '\n'))
+}
+
+/*
+VerifyManifest checks the hashes of the files listed in a manifest. The files are looked up in
+the manifest's directory. For a package, the manifest and files are read from the package.
+*/
+func VerifyManifest(manifestPath string) error {
+
+	// Files are hashed from the package or from the manifest's directory.
+	var data []byte
+	var err error
+	var hashListed func(name string) (string, error)
+
+	if pkg, zipErr := zip.OpenReader(manifestPath); zipErr == nil {
+
+		defer pkg.Close()
+		data, err = readPackageManifest(&pkg.Reader)
+		hashListed = func(name string) (string, error) {
+			return sha256Packaged(&pkg.Reader, name)
+		}
+
+	} else {
+
+		data, err = os.ReadFile(manifestPath)
+		hashListed = func(name string) (string, error) {
+			return sha256Listed(filepath.Join(filepath.Dir(manifestPath), name))
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("Unable to read the manifest: %v\n", err)
+		return err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		fmt.Printf("Invalid manifest: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("Collected %s on %s by %s with gobodyfile %s\n", m.Root, m.Host.Hostname, m.Host.User, m.Tool.Version)
+	fmt.Printf("Started %s, finished %s\n", m.Summary.Started.Format(time.RFC3339), m.Summary.Finished.Format(time.RFC3339))
+
+	failed := false
+
+	for _, file := range m.Files {
+
+		sum, err := hashListed(file.Name)
+
+		switch {
+		case os.IsNotExist(err):
+			fmt.Printf("MISSING   %s\n", file.Name)
+			failed = true
+		case err != nil:
+			fmt.Printf("ERROR     %s: %v\n", file.Name, err)
+			failed = true
+		case sum != file.SHA256:
+			fmt.Printf("MISMATCH  %s\n", file.Name)
+			failed = true
+		default:
+			fmt.Printf("OK        %s\n", file.Name)
+		}
+	}
+
+	if failed {
+		return ErrManifestMismatch
+	}
+
+	return nil
+}
+
+/*
+Reads the manifest of a package.
+*/
+func readPackageManifest(pkg *zip.Reader) ([]byte, error) {
+
+	for _, file := range pkg.File {
+
+		if !strings.HasSuffix(file.Name, ".manifest.json") {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+	}
+
+	return nil, fmt.Errorf("the package has no manifest")
+}
+
+/*
+Returns the SHA-256 of a file in a package.
+*/
+func sha256Packaged(pkg *zip.Reader, name string) (string, error) {
+
+	r, err := pkg.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	sum, _, err := sha256Reader(r)
+
+	return sum, err
+}
@@ -0,0 +1,67 @@
+package createBody
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+Returns what fn prints on stdout.
+*/
+func captureStdout(t *testing.T, fn func()) string {
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+
+	return <-done
+}
+
+func TestVerifyManifest(t *testing.T) {
+	defer func() { options = Options{} }()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+
+	outputFile := filepath.Join(t.TempDir(), "out.body")
+	if err := CreateBody([]string{dir}, outputFile, Options{}); err != nil {
+		t.Fatalf("CreateBody() error: %v", err)
+	}
+
+	if err := VerifyManifest(manifestFile(outputFile)); err != nil {
+		t.Fatalf("VerifyManifest() of an untouched collection error: %v", err)
+	}
+
+	// Flip a byte of the body file.
+	data, _ := os.ReadFile(outputFile)
+	data[len(data)-2] ^= 1
+	os.WriteFile(outputFile, data, 0644)
+
+	var err error
+	out := captureStdout(t, func() { err = VerifyManifest(manifestFile(outputFile)) })
+
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Errorf("VerifyManifest() error = %v, want ErrManifestMismatch", err)
+	}
+	if !strings.Contains(out, "MISMATCH  out.body\n") || !strings.Contains(out, "OK        out.body.errors.log\n") {
+		t.Errorf("VerifyManifest() printed %q, want a MISMATCH line for the body file only", out)
+	}
+}
@@ -0,0 +1,208 @@
+package createBody
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats of -compress.
+const (
+	compressNone = "none"
+	compressGzip = "gzip"
+	compressZstd = "zstd"
+)
+
+/*
+Returns the compression of the output. Without -compress, it is picked from the extension, which
+comes before .age for encrypted output (file.body.gz.age).
+*/
+func compression(outputFile string) string {
+
+	if options.Compress != "" {
+		return options.Compress
+	}
+
+	name := strings.TrimSuffix(outputFile, ".age")
+
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return compressGzip
+	case strings.HasSuffix(name, ".zst"), strings.HasSuffix(name, ".zstd"):
+		return compressZstd
+	}
+
+	return compressNone
+}
+
+/*
+Checks if the output is written as a plain text file, which is needed to append to it.
+*/
+func plainOutput(outputFile string) bool {
+	return compression(outputFile) == compressNone && len(options.Recipients) == 0 && options.RecipientsFile == "" && options.Package == ""
+}
+
+/*
+Returns the age recipients from -recipient and -recipients-file.
+*/
+func recipients() ([]age.Recipient, error) {
+
+	var all []age.Recipient
+
+	for _, r := range options.Recipients {
+
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+
+		recipient, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %s: %v", r, err)
+		}
+		all = append(all, recipient)
+	}
+
+	if options.RecipientsFile != "" {
+
+		f, err := os.Open(options.RecipientsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		fromFile, err := age.ParseRecipients(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipients file: %v", err)
+		}
+		all = append(all, fromFile...)
+	}
+
+	return all, nil
+}
+
+/*
+bodyWriter writes the body file through the compression and encryption layers.
+*/
+type bodyWriter struct {
+	*bufio.Writer
+	file   *os.File
+	layers []io.Closer
+}
+
+/*
+Opens the output. A new output replaces the file, otherwise it is appended to. With -package the
+body file is streamed into the package instead.
+*/
+func openOutput(outputFile string, create bool) (*bodyWriter, error) {
+
+	b := &bodyWriter{}
+	var w io.Writer
+
+	if options.Package != "" {
+
+		var err error
+		if evidence, err = createPackage(options.Package); err != nil {
+			return nil, err
+		}
+		if w, err = evidence.create(outputFile); err != nil {
+			closePackage()
+			return nil, err
+		}
+
+	} else {
+
+		flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		if create {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+
+		file, err := os.OpenFile(outputFile, flags, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		b.file = file
+		w = file
+	}
+
+	// Encrypt what was compressed since compressing encrypted data doesn't work.
+	to, err := recipients()
+	if err != nil {
+		b.abort()
+		return nil, err
+	}
+	if len(to) > 0 {
+		encrypted, err := age.Encrypt(w, to...)
+		if err != nil {
+			b.abort()
+			return nil, fmt.Errorf("could not encrypt the output: %v", err)
+		}
+		b.layers = append(b.layers, encrypted)
+		w = encrypted
+	}
+
+	switch compression(outputFile) {
+	case compressGzip:
+		zw := gzip.NewWriter(w)
+		b.layers = append(b.layers, zw)
+		w = zw
+	case compressZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			b.abort()
+			return nil, err
+		}
+		b.layers = append(b.layers, zw)
+		w = zw
+	case compressNone:
+	default:
+		b.abort()
+		return nil, fmt.Errorf("unknown compression %s (use gzip, zstd or none)", options.Compress)
+	}
+
+	b.Writer = bufio.NewWriterSize(w, 256*1024)
+
+	return b, nil
+}
+
+/*
+Closes the output file or package when it couldn't be set up.
+*/
+func (b *bodyWriter) abort() {
+
+	if b.file != nil {
+		b.file.Close()
+	}
+	closePackage()
+}
+
+/*
+Flushes the buffered entries and finishes the compression and encryption.
+*/
+func (b *bodyWriter) Close() error {
+
+	err := b.Flush()
+
+	for i := len(b.layers) - 1; i >= 0; i-- {
+		if closeErr := b.layers[i].Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	// The package stays open for the error log, summary and manifest.
+	if b.file == nil {
+		return err
+	}
+
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,93 @@
+package createBody
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+
+	"gobodyfile/common"
+)
+
+/*
+Writes an age identity to a file and returns the file and its recipient.
+*/
+func writeIdentity(t *testing.T, dir string, name string) (string, string) {
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	os.WriteFile(path, []byte(identity.String()+"\n"), 0600)
+
+	return path, identity.Recipient().String()
+}
+
+func TestOutputRoundTrip(t *testing.T) {
+	defer func() { options = Options{} }()
+
+	dir := t.TempDir()
+	identity, recipient := writeIdentity(t, dir, "key.txt")
+	wrong, _ := writeIdentity(t, dir, "other.txt")
+
+	const body = "0|/etc/passwd|1|33188|0|0|10|100|200|300|0\n0|/etc/group|2|33188|0|0|10|100|200|300|0\n"
+
+	tests := []struct {
+		file      string
+		compress  string
+		encrypted bool
+	}{
+		{"plain.body", "", false},
+		{"out.body.gz", "", false},
+		{"out.body.zst", "", false},
+		{"forced.body", "zstd", false},
+		{"out.body.gz.age", "", true},
+	}
+
+	for _, tt := range tests {
+
+		options = Options{Compress: tt.compress}
+		if tt.encrypted {
+			options.Recipients = []string{recipient}
+		}
+
+		path := filepath.Join(dir, tt.file)
+		out, err := openOutput(path, true)
+		if err != nil {
+			t.Fatalf("openOutput(%s) error: %v", tt.file, err)
+		}
+		out.WriteString(body)
+		if err := out.Close(); err != nil {
+			t.Fatalf("Close(%s) error: %v", tt.file, err)
+		}
+
+		r, err := common.OpenBody(path, identity)
+		if err != nil {
+			t.Fatalf("OpenBody(%s) error: %v", tt.file, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(data) != body {
+			t.Errorf("OpenBody(%s) read %q, %v, want the body written", tt.file, data, err)
+		}
+
+		if !tt.encrypted {
+			continue
+		}
+
+		// The wrong identity, or none at all, is an error rather than garbage.
+		for _, key := range []string{wrong, ""} {
+			if r, err := common.OpenBody(path, key); err == nil {
+				data, err := io.ReadAll(r)
+				r.Close()
+				if err == nil {
+					t.Errorf("OpenBody(%s) with identity %q read %d bytes, want an error", tt.file, key, len(data))
+				}
+			}
+		}
+	}
+}
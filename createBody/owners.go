@@ -0,0 +1,58 @@
+package createBody
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"gobodyfile/common"
+)
+
+/*
+Returns the name of the owners sidecar of a body file.
+*/
+func ownersFile(outputFile string) string {
+	return outputFile + ".owners.json"
+}
+
+/*
+Returns the root the passwd and group files are read from, the live system by default.
+*/
+func ownersRoot() string {
+
+	if options.OwnersRoot != "" {
+		return options.OwnersRoot
+	}
+
+	return "/"
+}
+
+/*
+Writes the owners sidecar with the accounts of the collected system. Body lines only carry the
+UIDs and GIDs, and a mounted image has other accounts than the system collecting it.
+*/
+func writeOwners(outputFile string) {
+
+	if !options.Owners {
+		return
+	}
+
+	owners, err := common.ReadAccounts(ownersRoot())
+	if err != nil {
+		logError(ownersRoot(), opRead, fmt.Errorf("failed to read the accounts: %w", err))
+		return
+	}
+
+	if root, err := filepath.Abs(owners.Root); err == nil {
+		owners.Root = root
+	}
+
+	data, err := json.MarshalIndent(owners, "", "  ")
+	if err != nil {
+		return
+	}
+
+	if err := writeSideFile(ownersFile(outputFile), append(data, '\n')); err != nil {
+		logError(outputFile, opWrite, fmt.Errorf("failed to write the owners sidecar: %w", err))
+	}
+}
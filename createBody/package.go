@@ -0,0 +1,184 @@
+package createBody
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*
+evidencePackage is the zip archive written with -package. The body file is streamed into it first,
+then the error log, summary and manifest are added when the collection ends. The hashes of the
+files are computed while they are written so nothing is read back.
+*/
+type evidencePackage struct {
+	file    *os.File
+	zw      *zip.Writer
+	files   []ManifestFile
+	current *packageEntry
+}
+
+/*
+packageEntry is the file being written to the package.
+*/
+type packageEntry struct {
+	w    io.Writer
+	h    hash.Hash
+	name string
+	size int64
+}
+
+// Package being written, nil unless -package is used.
+var evidence *evidencePackage
+
+// Error log kept in memory with -package until it is added to the package.
+var errorBuffer *bytes.Buffer
+
+/*
+Writes to the entry and its hash.
+*/
+func (e *packageEntry) Write(p []byte) (int, error) {
+
+	n, err := e.w.Write(p)
+	e.h.Write(p[:n])
+	e.size += int64(n)
+
+	return n, err
+}
+
+/*
+Creates the package, replacing an existing file.
+*/
+func createPackage(path string) (*evidencePackage, error) {
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &evidencePackage{file: file, zw: zip.NewWriter(file)}, nil
+}
+
+/*
+Adds a file to the package and returns a writer for its content. The zip format writes one file
+at a time, so the previous one is finished.
+*/
+func (p *evidencePackage) create(name string) (io.Writer, error) {
+
+	p.finishEntry()
+
+	w, err := p.zw.CreateHeader(&zip.FileHeader{Name: filepath.Base(name), Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	p.current = &packageEntry{w: w, h: sha256.New(), name: filepath.Base(name)}
+
+	return p.current, nil
+}
+
+/*
+Adds a file and its content to the package.
+*/
+func (p *evidencePackage) add(name string, data []byte) error {
+
+	w, err := p.create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+/*
+Records the hash of the file written last.
+*/
+func (p *evidencePackage) finishEntry() {
+
+	if p.current == nil {
+		return
+	}
+
+	p.files = append(p.files, ManifestFile{Name: p.current.name, Size: p.current.size, SHA256: hex.EncodeToString(p.current.h.Sum(nil))})
+	p.current = nil
+}
+
+/*
+Writes the zip directory and closes the package.
+*/
+func (p *evidencePackage) close() error {
+
+	p.finishEntry()
+
+	err := p.zw.Close()
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+/*
+Returns the file the collection writes to, which is the package with -package.
+*/
+func collectionFile(outputFile string) string {
+
+	if options.Package != "" {
+		return options.Package
+	}
+
+	return outputFile
+}
+
+/*
+Writes a file that goes with the body file. It is added to the package with -package, otherwise
+it is written next to the output.
+*/
+func writeSideFile(path string, data []byte) error {
+
+	if evidence != nil {
+		return evidence.add(path, data)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+/*
+Adds the error log to the package.
+*/
+func addErrorLog(outputFile string) {
+
+	if evidence == nil || errorBuffer == nil {
+		return
+	}
+
+	if err := evidence.add(errorLogFile(outputFile), errorBuffer.Bytes()); err != nil {
+		logError(outputFile, opWrite, err)
+	}
+
+	errorLog = nil
+	errorBuffer = nil
+}
+
+/*
+Closes the package once every file was added to it.
+*/
+func closePackage() error {
+
+	if evidence == nil {
+		return nil
+	}
+
+	err := evidence.close()
+	evidence = nil
+
+	return err
+}
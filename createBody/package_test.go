@@ -0,0 +1,79 @@
+package createBody
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"gobodyfile/common"
+)
+
+func TestPackage(t *testing.T) {
+	defer func() { options = Options{} }()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.php"), []byte("<?php"), 0644)
+
+	// Accounts of the collected system, like a mounted image.
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "etc"), 0755)
+	os.WriteFile(filepath.Join(root, "etc", "passwd"), []byte("root:x:0:0:root:/root:/bin/sh\n"), 0644)
+	os.WriteFile(filepath.Join(root, "etc", "group"), []byte("root:x:0:\n"), 0644)
+
+	pkg := filepath.Join(t.TempDir(), "host1.zip")
+	if err := CreateBody([]string{dir}, "host1.body", Options{Package: pkg, Owners: true, OwnersRoot: root}); err != nil {
+		t.Fatalf("CreateBody() error: %v", err)
+	}
+
+	zr, err := zip.OpenReader(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+	}
+	zr.Close()
+
+	// The body file comes first so it can be streamed, the manifest last to cover the others.
+	want := []string{"host1.body", "host1.body.errors.log", "host1.body.manifest.json", "host1.body.owners.json", "host1.body.summary.json"}
+	if len(names) == 0 || names[0] != "host1.body" || names[len(names)-1] != "host1.body.manifest.json" {
+		t.Errorf("package files = %v, want the body file first and the manifest last", names)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("package files = %v, want %v", names, want)
+	}
+
+	// timeline reads the body file and the sidecars straight from the package.
+	r, err := common.OpenBody(pkg, "")
+	if err != nil {
+		t.Fatalf("OpenBody() error: %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	r.Close()
+	if !strings.Contains(string(body), filepath.Join(dir, "index.php")) {
+		t.Errorf("body file in the package = %q, want the collected file", body)
+	}
+
+	f, _ := os.Open(pkg)
+	defer f.Close()
+	owners, err := common.PackageFile(f, ".owners.json")
+	if err != nil {
+		t.Fatalf("PackageFile(.owners.json) error: %v", err)
+	}
+	data, _ := io.ReadAll(owners)
+	owners.Close()
+	if !strings.Contains(string(data), "root") {
+		t.Errorf("owners sidecar = %q, want the accounts of the root", data)
+	}
+
+	// verify checks the files inside the package.
+	if err := VerifyManifest(pkg); err != nil {
+		t.Errorf("VerifyManifest() error: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package createBody
+
+import "strings"
+
+/*
+Returns the name written for a path. With -mount-prefix and -strip-prefix the name is rewritten
+to what it was on the original system, like fls -m: /mnt/evidence/etc/passwd is written as
+/etc/passwd or C:/etc/passwd. -normalize-paths turns backslashes into slashes.
+*/
+func entryName(path string) string {
+
+	name := path
+
+	if strip := stripPrefix(); strip != "" {
+		if rel, ok := cutPathPrefix(path, strip); ok {
+			name = joinMount(options.MountPrefix, rel)
+		}
+	}
+
+	if options.NormalizePaths {
+		name = strings.ReplaceAll(name, `\`, "/")
+	}
+
+	return name
+}
+
+/*
+Returns the prefix removed from the paths. It defaults to the collected directory when only
+-mount-prefix is given.
+*/
+func stripPrefix() string {
+
+	if options.StripPrefix != "" {
+		return options.StripPrefix
+	}
+
+	if options.MountPrefix != "" {
+		return stats.Directory
+	}
+
+	return ""
+}
+
+/*
+Removes prefix from path when it is one of its parent directories. Either separator matches so
+Windows paths can be given with slashes.
+*/
+func cutPathPrefix(path string, prefix string) (string, bool) {
+
+	slashed := strings.ReplaceAll(path, `\`, "/")
+	prefix = strings.TrimRight(strings.ReplaceAll(prefix, `\`, "/"), "/")
+
+	if slashed != prefix && !strings.HasPrefix(slashed, prefix+"/") {
+		return "", false
+	}
+
+	return strings.TrimLeft(path[len(prefix):], `/\`), true
+}
+
+/*
+Joins the mount prefix and the rest of the path. The names start at / when there is no mount
+prefix, and the mount prefix's own separator is used.
+*/
+func joinMount(mount string, rel string) string {
+
+	if mount == "" {
+		mount = "/"
+	}
+
+	if strings.HasSuffix(mount, "/") || strings.HasSuffix(mount, `\`) {
+		return mount + rel
+	}
+
+	if strings.Contains(mount, `\`) && !strings.Contains(mount, "/") {
+		return mount + `\` + rel
+	}
+
+	return mount + "/" + rel
+}
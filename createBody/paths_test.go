@@ -0,0 +1,32 @@
+package createBody
+
+import "testing"
+
+func TestEntryName(t *testing.T) {
+	defer func() { options = Options{}; stats = Summary{} }()
+
+	tests := []struct {
+		opts Options
+		dir  string
+		path string
+		want string
+	}{
+		{Options{}, "/mnt/evidence", "/mnt/evidence/etc/passwd", "/mnt/evidence/etc/passwd"},
+		{Options{MountPrefix: "/"}, "/mnt/evidence", "/mnt/evidence/etc/passwd", "/etc/passwd"},
+		{Options{MountPrefix: "/"}, "/mnt/evidence/", "/mnt/evidence", "/"},
+		{Options{MountPrefix: "C:/"}, "/mnt/win", "/mnt/win/Windows/System32", "C:/Windows/System32"},
+		{Options{StripPrefix: "/mnt/evidence"}, "/mnt/evidence/etc", "/mnt/evidence/etc/passwd", "/etc/passwd"},
+		{Options{StripPrefix: "/mnt/ev"}, "/mnt", "/mnt/evidence/etc/passwd", "/mnt/evidence/etc/passwd"},
+		{Options{MountPrefix: `C:\`, StripPrefix: `E:\image`}, `E:\image`, `E:\image\Users\x`, `C:\Users\x`},
+		{Options{MountPrefix: `C:`, StripPrefix: `E:/image`, NormalizePaths: true}, `E:\image`, `E:\image\Users\x`, `C:/Users/x`},
+		{Options{NormalizePaths: true}, `C:\Users`, `C:\Users\x`, `C:/Users/x`},
+	}
+
+	for _, tt := range tests {
+		options = tt.opts
+		stats = Summary{Directory: tt.dir}
+		if got := entryName(tt.path); got != tt.want {
+			t.Errorf("entryName(%q) with %+v = %q, want %q", tt.path, tt.opts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package createBody
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gobodyfile/common"
+)
+
+/*
+Profile bundles what to collect for a kind of incident: the directories, the paths included and
+excluded, the hashing policy and how deep to go. User profiles are [profile.<name>] tables of the
+configuration file and replace a built-in profile of the same name.
+*/
+type Profile struct {
+	Name        string
+	Description string
+	Directories []string
+	Include     []string
+	Exclude     []string
+	MD5         bool
+	HashMaxSize int64
+	MaxDepth    int
+}
+
+// Profiles shipped with gobodyfile.
+var builtinProfiles = []Profile{
+	{
+		Name:        "linux-triage",
+		Description: "Configuration, home directories, temporary directories, logs, scheduled tasks and local binaries of a Linux host",
+		Directories: []string{"/etc", "/root", "/home", "/tmp", "/var/tmp", "/dev/shm", "/var/spool/cron", "/var/log", "/usr/local/bin", "/usr/local/sbin", "/opt"},
+		Exclude:     []string{".cache", "node_modules"},
+		MD5:         true,
+		HashMaxSize: 50 << 20,
+		MaxDepth:    8,
+	},
+	{
+		Name:        "web-server",
+		Description: "Document roots, web server configuration and logs, and the temporary directories web shells are dropped in",
+		Directories: []string{"/var/www", "/srv", "/usr/share/nginx", "/etc/nginx", "/etc/apache2", "/etc/httpd", "/var/log/nginx", "/var/log/apache2", "/var/log/httpd", "/tmp", "/var/tmp", "/dev/shm"},
+		Exclude:     []string{".git"},
+		MD5:         true,
+		HashMaxSize: 20 << 20,
+	},
+	{
+		Name:        "persistence",
+		Description: "Places used to survive a reboot or a logout: cron, systemd, init scripts, shell startup files, SSH keys, sudoers, PAM, preloads and autostart entries",
+		Directories: []string{
+			"/etc/crontab", "/etc/cron.d", "/etc/cron.hourly", "/etc/cron.daily", "/etc/cron.weekly", "/etc/cron.monthly", "/var/spool/cron", "/etc/anacrontab",
+			"/etc/systemd", "/lib/systemd/system", "/usr/lib/systemd/system", "/etc/init.d", "/etc/rc.local",
+			"/etc/profile", "/etc/profile.d", "/etc/bash.bashrc", "/etc/environment",
+			"/etc/ld.so.preload", "/etc/ld.so.conf.d", "/etc/modules-load.d",
+			"/etc/sudoers", "/etc/sudoers.d", "/etc/ssh", "/etc/pam.d", "/etc/update-motd.d", "/etc/xdg/autostart",
+			"/root/.ssh", "/root/.bashrc", "/root/.profile", "/root/.bash_profile", "/root/.config/autostart", "/root/.config/systemd",
+			"/home/*/.ssh", "/home/*/.bashrc", "/home/*/.profile", "/home/*/.bash_profile", "/home/*/.config/autostart", "/home/*/.config/systemd",
+		},
+		MD5:      true,
+		MaxDepth: 4,
+	},
+}
+
+/*
+Profiles returns the built-in profiles and the ones of the configuration file, sorted by name.
+*/
+func Profiles(configFile string) ([]Profile, error) {
+
+	config, err := common.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Profile)
+	for _, p := range builtinProfiles {
+		byName[p.Name] = p
+	}
+
+	for table, values := range config {
+
+		name, ok := strings.CutPrefix(table, "profile.")
+		if !ok {
+			continue
+		}
+
+		p, err := parseProfile(name, values)
+		if err != nil {
+			return nil, err
+		}
+		byName[name] = *p
+	}
+
+	profiles := make([]Profile, 0, len(byName))
+	for _, p := range byName {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+
+	return profiles, nil
+}
+
+/*
+LoadProfile returns a profile by name.
+*/
+func LoadProfile(name string, configFile string) (*Profile, error) {
+
+	profiles, err := Profiles(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range profiles {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown profile %s, use -profile list to show the profiles", name)
+}
+
+/*
+Reads a [profile.<name>] table.
+*/
+func parseProfile(name string, values map[string]any) (*Profile, error) {
+
+	p := &Profile{Name: name}
+
+	for key, value := range values {
+
+		var ok bool
+		switch key {
+		case "description":
+			p.Description, ok = value.(string)
+		case "directories":
+			p.Directories, ok = value.([]string)
+		case "include":
+			p.Include, ok = value.([]string)
+		case "exclude":
+			p.Exclude, ok = value.([]string)
+		case "md5":
+			p.MD5, ok = value.(bool)
+		case "hash_max_size":
+			p.HashMaxSize, ok = value.(int64)
+		case "max_depth":
+			var depth int64
+			depth, ok = value.(int64)
+			p.MaxDepth = int(depth)
+		default:
+			return nil, fmt.Errorf("profile %s: unknown setting %s", name, key)
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("profile %s: invalid value for %s", name, key)
+		}
+	}
+
+	return p, nil
+}
+
+/*
+Apply sets the options of the profile that weren't given and returns the directories to collect.
+given holds the names of the options that were set, so -md5=false or -max-depth 0 still win over
+the profile. The profile's directories are used when none were given, leaving out the ones missing
+on this host. The includes and excludes are added to the ones given.
+*/
+func (p *Profile) Apply(dirs []string, opts *Options, given map[string]bool) []string {
+
+	opts.Profile = p.Name
+
+	if !given["md5"] {
+		opts.MD5 = p.MD5
+	}
+	if !given["hash-max-size"] {
+		opts.HashMaxSize = p.HashMaxSize
+	}
+	if !given["max-depth"] {
+		opts.MaxDepth = p.MaxDepth
+	}
+
+	opts.Include = append(opts.Include, p.Include...)
+	opts.Exclude = append(opts.Exclude, p.Exclude...)
+
+	if len(dirs) > 0 {
+		return dirs
+	}
+
+	for _, dir := range p.Directories {
+		if matches, _ := filepath.Glob(dir); len(matches) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+/*
+Checks if a path matches one of the patterns. A pattern with a slash is matched against the whole
+path, otherwise against the name.
+*/
+func matchPatterns(patterns []string, path string) bool {
+
+	slashed := filepath.ToSlash(path)
+	name := filepath.Base(path)
+
+	for _, pattern := range patterns {
+
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = slashed
+		}
+
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Returns the scope of the collection as written in the header, or an empty string when everything
+under the directories is collected and hashed.
+*/
+func collectionScope() string {
+
+	var parts []string
+
+	if len(options.Include) > 0 {
+		parts = append(parts, "include "+strings.Join(options.Include, ","))
+	}
+	if len(options.Exclude) > 0 {
+		parts = append(parts, "exclude "+strings.Join(options.Exclude, ","))
+	}
+	if options.MaxDepth > 0 {
+		parts = append(parts, fmt.Sprintf("max-depth %d", options.MaxDepth))
+	}
+	if options.MD5 && options.HashMaxSize > 0 {
+		parts = append(parts, fmt.Sprintf("hash-max-size %d", options.HashMaxSize))
+	}
+
+	return strings.Join(parts, "; ")
+}
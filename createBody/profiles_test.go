@@ -0,0 +1,58 @@
+package createBody
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(config, []byte(`
+[profile.web-server]
+directories = ["/srv/app"]
+include = ["*.php", "*.jsp"]
+max_depth = 3
+
+[profile.broken]
+md5 = "yes"
+`), 0644)
+
+	if _, err := LoadProfile("persistence", ""); err != nil {
+		t.Fatalf("built-in profile: %v", err)
+	}
+
+	// The broken profile is reported whichever profile is asked for.
+	if _, err := LoadProfile("web-server", config); err == nil {
+		t.Fatal("LoadProfile() didn't report the invalid md5 setting")
+	}
+
+	os.WriteFile(config, []byte("[profile.web-server]\ninclude = [\"*.php\"]\nmax_depth = 3\n"), 0644)
+	p, err := LoadProfile("web-server", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MaxDepth != 3 || len(p.Directories) != 0 {
+		t.Errorf("the config file didn't replace the built-in profile: %+v", p)
+	}
+
+	// Options given on the command line win over the profile.
+	opts := Options{Include: []string{"*.asp"}, MaxDepth: 1}
+	dirs := p.Apply([]string{"/var/www"}, &opts, map[string]bool{"include": true, "max-depth": true})
+	if len(dirs) != 1 || !reflect.DeepEqual(opts.Include, []string{"*.asp", "*.php"}) || opts.MaxDepth != 1 || opts.Profile != "web-server" {
+		t.Errorf("Apply() = %v, %+v", dirs, opts)
+	}
+
+	// Explicit zero and false values win too, and the rest comes from the profile.
+	triage, _ := LoadProfile("linux-triage", "")
+	opts = Options{}
+	triage.Apply(nil, &opts, map[string]bool{"md5": true, "max-depth": true})
+	if opts.MD5 || opts.MaxDepth != 0 || opts.HashMaxSize != triage.HashMaxSize {
+		t.Errorf("Apply() with -md5=false -max-depth 0 = %+v, want the profile's hashing turned off", opts)
+	}
+
+	if _, err := LoadProfile("nope", ""); err == nil {
+		t.Error("LoadProfile() found an unknown profile")
+	}
+}
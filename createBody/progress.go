@@ -0,0 +1,192 @@
+package createBody
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Time between updates of the progress line.
+const progressInterval = 250 * time.Millisecond
+
+/*
+Summary of a collection. It is printed at the end and written next to the output as JSON.
+*/
+type Summary struct {
+	Directory   string    `json:"directory"`
+	Output      string    `json:"output"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	Seconds     float64   `json:"seconds"`
+	Files       int       `json:"files"`
+	Directories int       `json:"directories"`
+	Skipped     int       `json:"skipped"`
+	BytesHashed int64     `json:"bytes_hashed"`
+	Errors      int       `json:"errors"`
+	// Errors by category (permission, vanished, io, no_space, too_many_files, other).
+	ErrorCategories map[string]int `json:"error_categories,omitempty"`
+	CriticalErrors  int            `json:"critical_errors"`
+	Interrupted     bool           `json:"interrupted"`
+}
+
+// Counts for the current collection.
+var stats Summary
+
+/*
+progress shows a live progress line on stderr when it is a terminal.
+*/
+type progress struct {
+	enabled  bool
+	total    int
+	position int
+	current  string
+	shown    time.Time
+}
+
+/*
+Resets the counts for a new collection.
+*/
+func resetStats(rootDir string, outputFile string) {
+	stats = Summary{Directory: rootDir, Output: outputFile, Started: time.Now()}
+}
+
+/*
+Returns the progress of a collection. It starts while walking the directories, which counts the
+paths to collect.
+*/
+func newProgress() *progress {
+
+	fd := os.Stderr.Fd()
+
+	// The first line is drawn after an interval so the rate means something.
+	return &progress{enabled: isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd), shown: time.Now()}
+}
+
+/*
+Counts a path found while walking the directories and redraws the line when it is due. Walking
+large trees takes a while before the first entry is collected.
+*/
+func (p *progress) found(path string) {
+
+	p.total++
+
+	if !p.enabled || time.Since(p.shown) < progressInterval {
+		return
+	}
+	p.shown = time.Now()
+
+	line := fmt.Sprintf("Walking: %d paths found, %s", p.total, path)
+	if len(line) > 160 {
+		line = line[:157] + "..."
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%s\033[K", line)
+}
+
+/*
+Moves the progress past a path and redraws the line when it is due.
+*/
+func (p *progress) advance(path string) {
+
+	p.position++
+	p.current = path
+
+	if !p.enabled || time.Since(p.shown) < progressInterval {
+		return
+	}
+	p.shown = time.Now()
+
+	elapsed := time.Since(stats.Started).Seconds()
+	rate := float64(stats.Files+stats.Directories) / elapsed
+
+	line := fmt.Sprintf("%.0f entries/s, %d files, %d dirs, %s hashed, %d errors", rate, stats.Files, stats.Directories, formatBytes(stats.BytesHashed), stats.Errors)
+
+	// The ETA is only known when the paths were counted first.
+	if p.total > 0 && p.position <= p.total && rate > 0 {
+		remaining := time.Duration(float64(p.total-p.position)/rate) * time.Second
+		line += fmt.Sprintf(", %d%%, ETA %s", p.position*100/p.total, remaining)
+	}
+
+	line += ", " + p.current
+
+	// Keep the line on one row of the terminal.
+	if len(line) > 160 {
+		line = line[:157] + "..."
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%s\033[K", line)
+}
+
+/*
+Clears the progress line.
+*/
+func (p *progress) done() {
+
+	if p.enabled {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+/*
+Formats a byte count with a binary unit.
+*/
+func formatBytes(n int64) string {
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(n)
+
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+/*
+Prints the summary of the collection and writes it to the output's .summary.json file.
+*/
+func writeSummary(outputFile string, interrupted bool) {
+
+	stats.Finished = time.Now()
+	stats.Seconds = stats.Finished.Sub(stats.Started).Seconds()
+	stats.Interrupted = interrupted
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Directory:    %s\n", stats.Directory)
+	fmt.Fprintf(&b, "Output:       %s\n", stats.Output)
+	fmt.Fprintf(&b, "Duration:     %s\n", stats.Finished.Sub(stats.Started).Round(time.Millisecond))
+	fmt.Fprintf(&b, "Files:        %d\n", stats.Files)
+	fmt.Fprintf(&b, "Directories:  %d\n", stats.Directories)
+	if stats.Skipped > 0 {
+		fmt.Fprintf(&b, "Resumed past: %d\n", stats.Skipped)
+	}
+	fmt.Fprintf(&b, "Hashed:       %s\n", formatBytes(stats.BytesHashed))
+	if stats.Errors > 0 {
+		fmt.Fprintf(&b, "Errors:       %d (%s)\n", stats.Errors, formatCategories(stats.ErrorCategories))
+	} else {
+		fmt.Fprintf(&b, "Errors:       0\n")
+	}
+	if interrupted {
+		fmt.Fprintf(&b, "Interrupted:  yes\n")
+	}
+	fmt.Print(b.String())
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return
+	}
+
+	if err := writeSideFile(outputFile+".summary.json", append(data, '\n')); err != nil {
+		fmt.Printf("Could not write the summary: %v\n", err)
+	}
+}
@@ -0,0 +1,262 @@
+package createBody
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The -directory values of the current collection, before their globs are expanded.
+var directories []string
+
+/*
+Returns the paths to collect: the directories with their globs expanded and the paths listed in
+-paths-from. A path given twice is only kept once. A glob matching nothing and a
+missing directory are logged.
+*/
+func collectionRoots(dirs []string) ([]string, error) {
+
+	var roots []string
+	added := make(map[string]bool)
+
+	add := func(path string) {
+		key := rootKey(path)
+		if !added[key] {
+			added[key] = true
+			roots = append(roots, path)
+		}
+	}
+
+	for _, dir := range dirs {
+
+		if !hasGlob(dir) {
+			if _, err := os.Lstat(dir); err != nil {
+				fmt.Printf("The directory doesn't exist! => %s\n", dir)
+				logError(dir, opWalk, fmt.Errorf("failed to access path: %w", err))
+				continue
+			}
+			add(dir)
+			continue
+		}
+
+		matches, err := filepath.Glob(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", dir, err)
+		}
+		if len(matches) == 0 {
+			fmt.Printf("No paths match %s\n", dir)
+			logError(dir, opWalk, fmt.Errorf("no paths match the pattern"))
+		}
+		for _, match := range matches {
+			add(match)
+		}
+	}
+
+	if options.PathsFrom != "" {
+
+		listed, err := readPathsFrom(options.PathsFrom)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the paths from %s: %v", options.PathsFrom, err)
+		}
+
+		// Listed paths are collected as given, a missing one is logged by the walk.
+		for _, path := range listed {
+			add(path)
+		}
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no paths to collect")
+	}
+
+	return roots, nil
+}
+
+/*
+Returns the path a root is compared with: absolute, and with its parent directories resolved so
+/lib/systemd and /usr/lib/systemd are the same when /lib links to /usr/lib. The root itself isn't
+resolved, a listed symlink is collected as the link.
+*/
+func rootKey(root string) string {
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return root
+	}
+
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(parent, filepath.Base(abs))
+	}
+
+	return abs
+}
+
+/*
+Checks if a -directory value is a glob pattern.
+*/
+func hasGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+/*
+Reads the paths listed in a file, or stdin for "-".
+*/
+func readPathsFrom(source string) ([]string, error) {
+
+	if source == "-" {
+		return readPathList(os.Stdin)
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readPathList(f)
+}
+
+/*
+Reads a list of paths, one per line or separated by NULs like find -print0 writes them. Empty
+lines are skipped.
+*/
+func readPathList(r io.Reader) ([]string, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := []byte("\n")
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	for _, line := range bytes.Split(data, sep) {
+		path := strings.TrimRight(string(line), "\r\n")
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+/*
+Walks each root like filepath.Walk. With several roots, a path under more than one of them, like
+/home and /home/alice/.ssh, is only visited once. Excluded paths, files that aren't included and
+paths deeper than -max-depth are left out. Returning filepath.SkipAll stops the walk of every root.
+*/
+func walkRoots(roots []string, fn filepath.WalkFunc) error {
+
+	var visited map[string]bool
+	if len(roots) > 1 {
+		visited = make(map[string]bool)
+	}
+
+	include, exclude := options.Include, options.Exclude
+
+	stop := false
+
+	for _, root := range roots {
+
+		abs := rootKey(root)
+		clean := filepath.Clean(root)
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			rel := path
+			if clean != "." {
+				rel = strings.TrimPrefix(path, clean)
+			}
+			rel = strings.Trim(rel, string(filepath.Separator))
+			if rel == "." {
+				rel = ""
+			}
+
+			if visited != nil {
+				key := filepath.Join(abs, rel)
+				if visited[key] {
+					if err == nil && info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				visited[key] = true
+			}
+
+			dir := err == nil && info.IsDir()
+
+			if matchPatterns(exclude, path) {
+				if dir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Directories are kept so the included files can be found under them.
+			if err == nil && !dir && len(include) > 0 && !matchPatterns(include, path) {
+				return nil
+			}
+
+			if err = fn(path, info, err); err == filepath.SkipAll {
+				stop = true
+			}
+
+			// The directories at the maximum depth are collected, but not what is under them.
+			if err == nil && dir && options.MaxDepth > 0 && pathDepth(rel) >= options.MaxDepth {
+				return filepath.SkipDir
+			}
+
+			return err
+		})
+
+		if err != nil || stop {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Returns the number of directories between a root and a path relative to it.
+*/
+func pathDepth(rel string) int {
+
+	if rel == "" {
+		return 0
+	}
+
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+/*
+Returns the roots as recorded in the header, summary and checkpoint: the directories as given,
+followed by the -paths-from source.
+*/
+func rootsLabel(absolute bool) string {
+
+	parts := make([]string, 0, len(directories)+1)
+
+	for _, dir := range directories {
+		if absolute {
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+		}
+		parts = append(parts, dir)
+	}
+
+	if options.PathsFrom == "-" {
+		parts = append(parts, "paths from stdin")
+	} else if options.PathsFrom != "" {
+		parts = append(parts, "paths from "+options.PathsFrom)
+	}
+
+	return strings.Join(parts, ", ")
+}
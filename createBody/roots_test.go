@@ -0,0 +1,92 @@
+package createBody
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPathList(t *testing.T) {
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"/etc/passwd\n/tmp\r\n\n/var/spool/cron\n", []string{"/etc/passwd", "/tmp", "/var/spool/cron"}},
+		{"/tmp/a b\x00/tmp/new\nline\x00", []string{"/tmp/a b", "/tmp/new\nline"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := readPathList(strings.NewReader(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readPathList(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestWalkRootsVisitsOnce(t *testing.T) {
+
+	dir := t.TempDir()
+	for _, path := range []string{"home/alice/.ssh/authorized_keys", "home/bob/.ssh/id_rsa", "tmp/x"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The .ssh directories are under the home root as well.
+	ssh, _ := filepath.Glob(filepath.Join(dir, "home", "*", ".ssh"))
+	roots := append(ssh, filepath.Join(dir, "home"), filepath.Join(dir, "tmp", "x"))
+
+	visits := make(map[string]int)
+	err := walkRoots(roots, func(path string, info os.FileInfo, err error) error {
+		visits[path]++
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, n := range visits {
+		if n != 1 {
+			t.Errorf("%s visited %d times", path, n)
+		}
+	}
+
+	// home, alice, bob, both .ssh, both keys and tmp/x.
+	if len(visits) != 8 {
+		t.Errorf("visited %d paths, want 8: %v", len(visits), visits)
+	}
+}
+
+func TestWalkRootsScope(t *testing.T) {
+	defer func() { options = Options{} }()
+
+	dir := t.TempDir()
+	for _, path := range []string{"www/index.php", "www/style.css", "www/node_modules/x.php", "www/a/b/deep.php"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755)
+		os.WriteFile(filepath.Join(dir, path), nil, 0644)
+	}
+
+	options = Options{Include: []string{"*.php"}, Exclude: []string{"node_modules"}, MaxDepth: 2}
+
+	var got []string
+	walkRoots([]string{filepath.Join(dir, "www")}, func(path string, info os.FileInfo, err error) error {
+		rel, _ := filepath.Rel(dir, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+
+	want := []string{"www", "www/a", "www/a/b", "www/index.php"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walkRoots() visited %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+//go:build linux
+
+package createBody
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"gobodyfile/common"
+)
+
+// Events that cause a path to be stat'ed again. Access events are left out since
+// hashing files would cause more of them.
+const watchMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DONT_FOLLOW
+
+// Size of the fixed part of struct inotify_event.
+const inotifyEventSize = syscall.SizeofInotifyEvent
+
+/*
+watcher holds the inotify instance and the directory of each watch.
+*/
+type watcher struct {
+	fd         int
+	dirs       map[int32]string
+	limitHit   bool
+	outputFile string
+	out        *bodyWriter
+	ignore     map[string]bool
+}
+
+/*
+Adds a watch on each directory under root. When the watch limit is reached, the remaining
+directories aren't watched and it is reported once in the error log.
+*/
+func (w *watcher) addWatches(root string) {
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			logError(path, opWalk, fmt.Errorf("failed to access path: %w", err))
+			return nil
+		}
+
+		if !info.IsDir() || w.limitHit {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, watchMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				w.limitHit = true
+				logError(path, opWatch, fmt.Errorf("inotify watch limit reached (see fs.inotify.max_user_watches), directories from here on are not watched: %w", err))
+				return filepath.SkipDir
+			}
+			logError(path, opWatch, fmt.Errorf("failed to add watch: %w", err))
+			return nil
+		}
+
+		w.dirs[int32(wd)] = path
+
+		return nil
+	})
+}
+
+/*
+Stats a path and every path under it. Used for new directories since files can be created
+in them before their watch is added.
+*/
+func (w *watcher) statTree(root string) {
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			logError(path, opWalk, fmt.Errorf("failed to access path: %w", err))
+			return nil
+		}
+
+		w.stat(path)
+
+		return nil
+	})
+}
+
+/*
+Checks if the path is the output or error log, which change each time an entry is written.
+*/
+func (w *watcher) ignored(path string) bool {
+
+	abs, err := filepath.Abs(path)
+
+	return err == nil && w.ignore[abs]
+}
+
+/*
+Stats a path and appends it to the output.
+*/
+func (w *watcher) stat(path string) {
+
+	if w.ignored(path) {
+		return
+	}
+
+	// Error already logged in statFDir.
+	statFDir(path, w.out)
+}
+
+/*
+Appends a tombstone for a deleted or moved path.
+*/
+func (w *watcher) deleted(path string) {
+
+	if w.ignored(path) {
+		return
+	}
+
+	if err := writeTombstone(w.out, &entry{md5: "0", name: entryName(path), mode: "0", uid: "0", gid: "0"}); err != nil {
+		logError(path, opWrite, fmt.Errorf("failed to write to output file: %w", err))
+	}
+}
+
+/*
+Handles a single inotify event.
+*/
+func (w *watcher) handle(wd int32, mask uint32, name string) {
+
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		logError(w.outputFile, opWatch, fmt.Errorf("inotify event queue overflowed, events were lost"))
+		return
+	}
+
+	dir, ok := w.dirs[wd]
+	if !ok {
+		return
+	}
+
+	// The watch was removed because the directory was deleted or unmounted.
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.dirs, wd)
+		return
+	}
+
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+
+	switch {
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		w.deleted(path)
+	case mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		w.addWatches(path)
+		w.statTree(path)
+	default:
+		w.stat(path)
+	}
+
+	// Creating, deleting or moving an entry also changes its directory.
+	if mask&(syscall.IN_CREATE|syscall.IN_DELETE|syscall.IN_MOVED_FROM|syscall.IN_MOVED_TO) != 0 {
+		w.stat(dir)
+	}
+}
+
+/*
+Watch appends a body line each time a path under rootDir changes until it is interrupted. The
+output is opened like a collection's, -force replaces it and -append adds to it.
+*/
+func Watch(rootDir string, outputFile string, opts Options) {
+
+	options = opts
+	directories = []string{rootDir}
+
+	// Initialize error logging.
+	if err := initErrorLog(outputFile); err != nil {
+		fmt.Printf("Warning: Could not create error log: %v\n", err)
+	}
+	defer closeErrorLog()
+
+	// Check if the directory exists.
+	common.CheckDirectoryExists(rootDir)
+
+	// Check if the output file exists.
+	if err := common.CheckOutputFile(collectionFile(outputFile), options.Force, options.Append); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// The descriptor is non-blocking so reading it can be interrupted with a deadline.
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		fmt.Printf("Unable to initialize inotify: %v\n", err)
+		return
+	}
+	events := os.NewFile(uintptr(fd), "inotify")
+	defer events.Close()
+
+	// Skip repeated events for paths whose metadata didn't change.
+	previous = make(map[string]*entry)
+	seen = make(map[string]bool)
+
+	w := &watcher{fd: fd, dirs: make(map[int32]string), outputFile: outputFile, ignore: make(map[string]bool)}
+
+	// Writing the output would cause events of its own when it is under rootDir.
+	for _, path := range []string{outputFile, errorLogFile(outputFile)} {
+		if abs, err := filepath.Abs(path); err == nil {
+			w.ignore[abs] = true
+		}
+	}
+
+	// Entries are appended as they happen, which compressed or encrypted output can't do.
+	if !plainOutput(outputFile) {
+		fmt.Println("Watch mode only writes uncompressed and unencrypted output outside a package.")
+		return
+	}
+
+	out, err := openBodyOutput(outputFile, !options.Append)
+	if err != nil {
+		fmt.Printf("Unable to open the output: %v\n", err)
+		return
+	}
+	defer out.Close()
+	w.out = out
+
+	w.addWatches(rootDir)
+	fmt.Printf("Watching %d directories under %s, press Ctrl-C to stop.\n", len(w.dirs), rootDir)
+
+	// Stop cleanly on Ctrl-C or SIGTERM. The read is interrupted and the loop returns, so the
+	// output and error log are closed once the events being handled are written.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	go func() {
+		<-stop
+		events.SetReadDeadline(time.Now())
+	}()
+
+	buf := make([]byte, 64*1024)
+
+	for {
+
+		// Entries are written as they happen.
+		if err := out.Flush(); err != nil {
+			logError(outputFile, opWrite, fmt.Errorf("failed to write to output file: %w", err))
+		}
+
+		n, err := events.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return
+		}
+		if err != nil {
+			fmt.Printf("Unable to read inotify events: %v\n", err)
+			return
+		}
+
+		for offset := 0; offset+inotifyEventSize <= n; {
+
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			length := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+
+			name := strings.TrimRight(string(buf[offset+inotifyEventSize:offset+inotifyEventSize+length]), "\x00")
+			offset += inotifyEventSize + length
+
+			w.handle(wd, mask, name)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package createBody
+
+import "fmt"
+
+/*
+Watch is only supported on Linux since it relies on inotify.
+*/
+func Watch(rootDir string, outputFile string, opts Options) {
+
+	fmt.Println("Watch mode is only supported on Linux.")
+
+}
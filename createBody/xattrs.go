@@ -0,0 +1,104 @@
+package createBody
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"gobodyfile/common"
+)
+
+// Sidecar the extended attributes are written to, or the in-memory sidecar with -package.
+var xattrLog io.Writer
+
+// Sidecar kept in memory with -package until it is added to the package.
+var xattrBuffer *bytes.Buffer
+
+/*
+Returns the name of the xattrs sidecar of a body file.
+*/
+func xattrsFile(outputFile string) string {
+	return outputFile + ".xattrs.jsonl"
+}
+
+/*
+Checks if the extended attributes are collected. They are only read on Linux.
+*/
+func collectXattrs() bool {
+	return options.Xattrs && runtime.GOOS == "linux"
+}
+
+/*
+Opens the xattrs sidecar. A new collection replaces it, a resumed one appends to it.
+*/
+func openXattrs(outputFile string, create bool) error {
+
+	if !collectXattrs() {
+		if options.Xattrs {
+			fmt.Println("-xattrs is only supported on Linux, extended attributes are not collected.")
+		}
+		return nil
+	}
+
+	if options.Package != "" {
+		xattrBuffer = new(bytes.Buffer)
+		xattrLog = xattrBuffer
+		return nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if create {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(xattrsFile(outputFile), flags, 0644)
+	if err != nil {
+		return err
+	}
+	xattrLog = f
+
+	return nil
+}
+
+/*
+Writes the extended attributes of a path to the sidecar.
+*/
+func writeXattrs(record *common.XattrRecord) {
+
+	if xattrLog == nil {
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	if _, err := xattrLog.Write(append(data, '\n')); err != nil {
+		logError(record.Path, opWrite, fmt.Errorf("failed to write the xattrs sidecar: %w", err))
+	}
+}
+
+/*
+Closes the sidecar, or adds it to the package with -package.
+*/
+func closeXattrs(outputFile string) {
+
+	if xattrBuffer != nil && evidence != nil {
+		if err := evidence.add(xattrsFile(outputFile), xattrBuffer.Bytes()); err != nil {
+			logError(outputFile, opWrite, err)
+		}
+	}
+
+	if f, ok := xattrLog.(*os.File); ok {
+		if err := f.Close(); err != nil {
+			logError(outputFile, opWrite, fmt.Errorf("failed to close the xattrs sidecar: %w", err))
+		}
+	}
+
+	xattrLog = nil
+	xattrBuffer = nil
+}
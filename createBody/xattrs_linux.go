@@ -0,0 +1,112 @@
+package createBody
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+
+	"gobodyfile/common"
+)
+
+/*
+Reads the extended attributes of a path without following symlinks and writes them to the
+sidecar. Paths without extended attributes are left out.
+*/
+func readXattrs(path string, inode uint64) {
+
+	names, err := listXattrs(path)
+	if err != nil {
+		// File systems without extended attributes have nothing to collect.
+		if !errors.Is(err, unix.ENOTSUP) {
+			logError(path, opXattr, fmt.Errorf("failed to list extended attributes: %w", err))
+		}
+		return
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	record := &common.XattrRecord{Path: entryName(path), Inode: inode}
+
+	for _, name := range names {
+
+		value, err := getXattr(path, name)
+
+		// The attribute was removed after it was listed.
+		if errors.Is(err, unix.ENODATA) {
+			continue
+		}
+		if err != nil {
+			logError(path, opXattr, fmt.Errorf("failed to read extended attribute %s: %w", name, err))
+			continue
+		}
+
+		record.Add(name, value)
+	}
+
+	writeXattrs(record)
+}
+
+/*
+Returns the names of the extended attributes of a path.
+*/
+func listXattrs(path string) ([]string, error) {
+
+	buf, err := readSized(func(b []byte) (int, error) {
+		return unix.Llistxattr(path, b)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+
+	return names, nil
+}
+
+/*
+Returns the value of an extended attribute.
+*/
+func getXattr(path string, name string) ([]byte, error) {
+
+	return readSized(func(b []byte) (int, error) {
+		return unix.Lgetxattr(path, name, b)
+	})
+}
+
+/*
+Calls an xattr syscall first to get the size, then to read the data. The data can grow in
+between, so it is retried when the buffer is too small.
+*/
+func readSized(call func(b []byte) (int, error)) ([]byte, error) {
+
+	for tries := 0; ; tries++ {
+
+		size, err := call(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, size)
+		n, err := call(buf)
+		if errors.Is(err, unix.ERANGE) && tries < 3 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gobodyfile/common"
+	"gobodyfile/diffBody"
+)
+
+/*
+Compares two body files.
+*/
+func runDiff(args []string) {
+
+	fs := newFlagSet("diff", "old.body new.body", "")
+
+	// Variables for comparing two body files.
+	var format string
+	var outputFile string
+	var identity string
+	var force bool
+
+	fs.StringVar(&format, "format", "text", "Output format (text, json, body)")
+	fs.StringVar(&outputFile, "output", "", "(Optional) Output file name. Default writes to stdout.")
+	fs.BoolVar(&force, "force", false, "(Optional) Replace the output file when it exists.")
+	fs.StringVar(&identity, "identity", "", "(Optional) age identity file to read encrypted body files.")
+
+	args = parseFlags(fs, args)
+
+	// Check that the old and new body files were provided.
+	if len(args) != 2 {
+
+		fmt.Println("Provide the old and new body files to compare: diff [options] old.body new.body")
+		return
+
+	}
+
+	// Check if the output file exists.
+	if outputFile != "" {
+
+		if err := common.CheckOutputFile(outputFile, force, false); err != nil {
+
+			fmt.Println(err)
+			os.Exit(1)
+
+		}
+
+	}
+
+	// Compare the body files.
+	diffBody.DiffBody(args[0], args[1], format, outputFile, identity)
+}
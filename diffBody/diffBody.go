@@ -0,0 +1,276 @@
+package diffBody
+
+/*
+Compares two body files of the same system (before/after patching, baseline vs. incident)
+and reports the entries that were added, removed, renamed or modified.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
+)
+
+// Change types.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Renamed  = "renamed"
+	Modified = "modified"
+)
+
+// Classes of modifications.
+const (
+	ContentChange   = "content"
+	MetadataChange  = "metadata"
+	TimestampChange = "timestamps"
+)
+
+// Change is a single difference between the two body files.
+type Change struct {
+	Change  string          `json:"change"`
+	Path    string          `json:"path"`
+	From    string          `json:"from,omitempty"`
+	Classes []string        `json:"classes,omitempty"`
+	Old     *bodyfile.Entry `json:"old,omitempty"`
+	New     *bodyfile.Entry `json:"new,omitempty"`
+}
+
+/*
+readEntries reads a body file and returns its entries keyed by path. Compressed and encrypted body
+files are decoded, identityFile is only needed for encrypted ones.
+*/
+func readEntries(bodyFile string, identityFile string) (map[string]*bodyfile.Entry, error) {
+
+	f, err := common.OpenBody(bodyFile, identityFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := common.BodyEntries(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", bodyFile, err)
+	}
+
+	entries := make(map[string]*bodyfile.Entry)
+	body := bodyfile.NewReader(r)
+
+	for {
+		e, err := body.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", bodyFile, err)
+		}
+		entries[e.Name] = e
+	}
+
+	return entries, nil
+}
+
+/*
+hasHash checks if the body file recorded an MD5 for the entry.
+*/
+func hasHash(e *bodyfile.Entry) bool {
+	return e.MD5 != "" && e.MD5 != "0"
+}
+
+/*
+Classify returns the classes of changes between two versions of an entry.
+*/
+func Classify(before *bodyfile.Entry, after *bodyfile.Entry) []string {
+
+	var classes []string
+
+	if before.Size != after.Size || (hasHash(before) && hasHash(after) && before.MD5 != after.MD5) {
+		classes = append(classes, ContentChange)
+	}
+
+	if before.Mode != after.Mode || before.UID != after.UID || before.GID != after.GID {
+		classes = append(classes, MetadataChange)
+	}
+
+	if !before.AccessTime.Equal(after.AccessTime) || !before.ModificationTime.Equal(after.ModificationTime) ||
+		!before.ChangeTime.Equal(after.ChangeTime) || !before.CreationTime.Equal(after.CreationTime) {
+		classes = append(classes, TimestampChange)
+	}
+
+	return classes
+}
+
+/*
+Diff compares the old and new entries. Entries removed from one path and added under another
+with the same inode are reported as renamed.
+*/
+func Diff(oldEntries map[string]*bodyfile.Entry, newEntries map[string]*bodyfile.Entry) []Change {
+
+	changes := []Change{}
+
+	// Removed entries by inode so moves can be matched to them.
+	removedInodes := make(map[int]*bodyfile.Entry)
+
+	for path, before := range oldEntries {
+		if _, ok := newEntries[path]; !ok && before.Inode != 0 {
+			removedInodes[before.Inode] = before
+		}
+	}
+
+	// Walk the new paths in order so hard links are matched the same way every time.
+	paths := make([]string, 0, len(newEntries))
+	for path := range newEntries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	renamedFrom := make(map[string]bool)
+
+	for _, path := range paths {
+
+		after := newEntries[path]
+
+		before, ok := oldEntries[path]
+		if !ok {
+
+			// Inode 0 is used when the collector couldn't get the inode.
+			if moved, found := removedInodes[after.Inode]; found && after.Inode != 0 && !renamedFrom[moved.Name] {
+				renamedFrom[moved.Name] = true
+				changes = append(changes, Change{Change: Renamed, Path: path, From: moved.Name, Classes: Classify(moved, after), Old: moved, New: after})
+				continue
+			}
+
+			changes = append(changes, Change{Change: Added, Path: path, New: after})
+			continue
+		}
+
+		if classes := Classify(before, after); len(classes) > 0 {
+			changes = append(changes, Change{Change: Modified, Path: path, Classes: classes, Old: before, New: after})
+		}
+	}
+
+	for path, before := range oldEntries {
+		if _, ok := newEntries[path]; !ok && !renamedFrom[path] {
+			changes = append(changes, Change{Change: Removed, Path: path, Old: before})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+/*
+BodyLine returns the entry in the body file format.
+*/
+func BodyLine(e *bodyfile.Entry) string {
+
+	return fmt.Sprintf("%s|%s|%d|%s|%d|%d|%d|%d|%d|%d|%d", e.MD5, e.Name, e.Inode, e.Mode, e.UID, e.GID, e.Size,
+		e.AccessTime.Unix(), e.ModificationTime.Unix(), e.ChangeTime.Unix(), e.CreationTime.Unix())
+}
+
+/*
+writeText writes the changes one per line followed by the number of each type of change.
+*/
+func writeText(w io.Writer, changes []Change) error {
+
+	counts := make(map[string]int)
+
+	for _, c := range changes {
+
+		counts[c.Change]++
+
+		line := fmt.Sprintf("%-9s %s", c.Change, c.Path)
+		if c.Change == Renamed {
+			line = fmt.Sprintf("%-9s %s -> %s", c.Change, c.From, c.Path)
+		}
+		if len(c.Classes) > 0 {
+			line += fmt.Sprintf(" [%s]", strings.Join(c.Classes, ","))
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d added, %d removed, %d renamed, %d modified\n", counts[Added], counts[Removed], counts[Renamed], counts[Modified])
+	return err
+}
+
+/*
+writeBody writes the new version of every added, renamed or modified entry as a body file.
+Removed entries have no new version and are left out.
+*/
+func writeBody(w io.Writer, changes []Change) error {
+
+	for _, c := range changes {
+		if c.New == nil {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, BodyLine(c.New)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+DiffBody compares two body files and writes the changes as text, json or a body file.
+*/
+func DiffBody(oldFile string, newFile string, format string, outputFile string, identityFile string) {
+
+	if format != "text" && format != "json" && format != "body" {
+		fmt.Printf("Unknown format: %s (use text, json or body)\n", format)
+		os.Exit(1)
+	}
+
+	oldEntries, err := readEntries(oldFile, identityFile)
+	if err != nil {
+		fmt.Printf("Could not read the old body file: %v\n", err)
+		os.Exit(1)
+	}
+
+	newEntries, err := readEntries(newFile, identityFile)
+	if err != nil {
+		fmt.Printf("Could not read the new body file: %v\n", err)
+		os.Exit(1)
+	}
+
+	changes := Diff(oldEntries, newEntries)
+
+	out := os.Stdout
+	if outputFile != "" {
+		out, err = os.Create(outputFile)
+		if err != nil {
+			fmt.Printf("Could not create the output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(changes)
+	case "body":
+		err = writeBody(out, changes)
+	default:
+		err = writeText(out, changes)
+	}
+
+	if err != nil {
+		fmt.Printf("Could not write the changes: %v\n", err)
+		os.Exit(1)
+	}
+}
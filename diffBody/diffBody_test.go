@@ -0,0 +1,54 @@
+package diffBody
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/airbus-cert/bodyfile"
+)
+
+func entry(name string, inode int, size int64, mtime int64) *bodyfile.Entry {
+	t := time.Unix(mtime, 0).UTC()
+	return &bodyfile.Entry{MD5: "0", Name: name, Inode: inode, Mode: "420", Size: size,
+		AccessTime: t, ModificationTime: t, ChangeTime: t, CreationTime: t}
+}
+
+func TestDiff(t *testing.T) {
+	oldEntries := map[string]*bodyfile.Entry{
+		"/etc/passwd":  entry("/etc/passwd", 10, 100, 1000),
+		"/etc/shadow":  entry("/etc/shadow", 11, 50, 1000),
+		"/tmp/dropper": entry("/tmp/dropper", 12, 4096, 1000),
+		"/tmp/gone":    entry("/tmp/gone", 13, 1, 1000),
+	}
+
+	chmod := entry("/etc/shadow", 11, 50, 1000)
+	chmod.Mode = "511"
+
+	newEntries := map[string]*bodyfile.Entry{
+		"/etc/passwd":        entry("/etc/passwd", 10, 120, 2000),
+		"/etc/shadow":        chmod,
+		"/usr/bin/.dropper":  entry("/usr/bin/.dropper", 12, 4096, 1000),
+		"/var/www/shell.php": entry("/var/www/shell.php", 14, 10, 2000),
+	}
+
+	expected := []Change{
+		{Change: Modified, Path: "/etc/passwd", Classes: []string{ContentChange, TimestampChange}},
+		{Change: Modified, Path: "/etc/shadow", Classes: []string{MetadataChange}},
+		{Change: Removed, Path: "/tmp/gone"},
+		{Change: Renamed, Path: "/usr/bin/.dropper", From: "/tmp/dropper"},
+		{Change: Added, Path: "/var/www/shell.php"},
+	}
+
+	changes := Diff(oldEntries, newEntries)
+	if len(changes) != len(expected) {
+		t.Fatalf("Diff returned %d changes, want %d: %+v", len(changes), len(expected), changes)
+	}
+
+	for i, c := range changes {
+		if c.Change != expected[i].Change || c.Path != expected[i].Path || c.From != expected[i].From || !reflect.DeepEqual(c.Classes, expected[i].Classes) {
+			t.Errorf("change %d = %s %s (from %q) %v, want %s %s (from %q) %v", i, c.Change, c.Path, c.From, c.Classes,
+				expected[i].Change, expected[i].Path, expected[i].From, expected[i].Classes)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"gobodyfile/indexBody"
+)
+
+/*
+Adds body files to an index database.
+*/
+func runIndex(args []string) {
+
+	fs := newFlagSet("index", "bodyfile.txt...", "")
+
+	// Variable for the index database.
+	var dbPath string
+	var identity string
+
+	fs.StringVar(&dbPath, "db", "", "Index database file")
+	fs.StringVar(&identity, "identity", "", "(Optional) age identity file to read encrypted body files.")
+
+	args = parseFlags(fs, args)
+
+	// Check if the database value is empty.
+	if dbPath == "" {
+
+		fmt.Println("Index database not provided. Use -db and provide the database file's name.")
+		return
+
+	}
+
+	// Check if any body files were provided.
+	if len(args) == 0 {
+
+		fmt.Println("No body files provided. Pass one or more body files after the options.")
+		return
+
+	}
+
+	// Add the body files to the index.
+	indexBody.IndexBody(dbPath, args, identity)
+}
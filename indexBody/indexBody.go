@@ -0,0 +1,462 @@
+package indexBody
+
+/*
+Stores body file entries in an on-disk bbolt database so the same evidence can be
+queried repeatedly without re-parsing and re-sorting the body file each time.
+
+The database has the following buckets:
+
+	entries => id | JSON encoded entry
+	time    => timestamp | id | MACB type of the timestamp
+	path    => name | id
+	hash    => MD5 | id
+	sources => SHA-256 of the body file | JSON encoded name and number of entries indexed
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/airbus-cert/bodyfile"
+	bolt "go.etcd.io/bbolt"
+
+	"gobodyfile/common"
+)
+
+var (
+	entriesBucket = []byte("entries")
+	timeBucket    = []byte("time")
+	pathBucket    = []byte("path")
+	hashBucket    = []byte("hash")
+	sourcesBucket = []byte("sources")
+)
+
+// Number of entries written per transaction while indexing.
+var batchSize = 10000
+
+// ErrIndexed is returned by Add for a body file whose content is already in the index.
+var ErrIndexed = errors.New("already in the index")
+
+// source is a body file added to the index.
+type source struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+}
+
+// Index is an open index database.
+type Index struct {
+	db *bolt.DB
+}
+
+/*
+Open opens the index database. The database is created when create is true.
+*/
+func Open(dbPath string, create bool) (*Index, error) {
+
+	if !create {
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil, fmt.Errorf("index database not found: %v", err)
+		}
+	}
+
+	db, err := bolt.Open(dbPath, 0644, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: !create})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index database: %v", err)
+	}
+
+	if create {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{entriesBucket, timeBucket, pathBucket, hashBucket, sourcesBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create index buckets: %v", err)
+		}
+	}
+
+	return &Index{db: db}, nil
+}
+
+/*
+Close closes the index database.
+*/
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+/*
+Events returns the distinct timestamps of an entry along with the MACB type of each one.
+Timestamps shared by several types are only returned once, the same way bodyfile.Slurp does.
+*/
+func Events(e *bodyfile.Entry) map[int]time.Time {
+
+	events := map[int]time.Time{bodyfile.AccessTime: e.AccessTime}
+
+	if e.ModificationTime != e.AccessTime {
+		events[bodyfile.ModificationTime] = e.ModificationTime
+	}
+	if e.ChangeTime != e.ModificationTime && e.ChangeTime != e.AccessTime {
+		events[bodyfile.ChangeTime] = e.ChangeTime
+	}
+	if e.CreationTime != e.ModificationTime && e.CreationTime != e.ChangeTime && e.CreationTime != e.AccessTime {
+		events[bodyfile.CreationTime] = e.CreationTime
+	}
+
+	return events
+}
+
+/*
+Returns the key used for the time bucket. The sign bit is flipped so negative timestamps sort first.
+*/
+func timeKey(t int64, id uint64) []byte {
+
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t)^(1<<63))
+	binary.BigEndian.PutUint64(key[8:], id)
+
+	return key
+}
+
+/*
+Returns the key used for the path and hash buckets.
+*/
+func lookupKey(value string, id uint64) []byte {
+
+	key := make([]byte, 0, len(value)+9)
+	key = append(key, value...)
+	key = append(key, 0)
+
+	return binary.BigEndian.AppendUint64(key, id)
+}
+
+/*
+Adds a single entry and its index keys.
+*/
+func putEntry(tx *bolt.Tx, e *bodyfile.Entry) error {
+
+	entries := tx.Bucket(entriesBucket)
+
+	id, err := entries.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	idKey := binary.BigEndian.AppendUint64(nil, id)
+	if err := entries.Put(idKey, data); err != nil {
+		return err
+	}
+
+	for kind, t := range Events(e) {
+		if err := tx.Bucket(timeBucket).Put(timeKey(t.Unix(), id), []byte{byte(kind)}); err != nil {
+			return err
+		}
+	}
+
+	if hashed(e) {
+		if err := tx.Bucket(hashBucket).Put(lookupKey(e.MD5, id), nil); err != nil {
+			return err
+		}
+	}
+
+	return tx.Bucket(pathBucket).Put(lookupKey(e.Name, id), nil)
+}
+
+/*
+Checks if the entry has an MD5. Body files without hashes use 0 for the MD5.
+*/
+func hashed(e *bodyfile.Entry) bool {
+	return e.MD5 != "" && e.MD5 != "0"
+}
+
+/*
+Removes the entries from the given id on, along with their index keys.
+*/
+func removeEntries(tx *bolt.Tx, first uint64) error {
+
+	var ids [][]byte
+
+	c := tx.Bucket(entriesBucket).Cursor()
+	for k, _ := c.Seek(binary.BigEndian.AppendUint64(nil, first)); k != nil; k, _ = c.Next() {
+		ids = append(ids, k)
+	}
+
+	for _, idKey := range ids {
+
+		e, err := getEntry(tx, idKey)
+		if err != nil {
+			return err
+		}
+
+		id := binary.BigEndian.Uint64(idKey)
+		for _, t := range Events(e) {
+			if err := tx.Bucket(timeBucket).Delete(timeKey(t.Unix(), id)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(pathBucket).Delete(lookupKey(e.Name, id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(hashBucket).Delete(lookupKey(e.MD5, id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(entriesBucket).Delete(idKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Add reads a body file and adds its entries to the index. Body files are recognized by the SHA-256
+of their content, so the same content read under another name or from stdin returns ErrIndexed.
+Nothing is left in the index when the body file can't be read to the end.
+*/
+func (ix *Index) Add(name string, r io.Reader) (int, error) {
+
+	// The source is only known once the whole content was read.
+	h := sha256.New()
+
+	// The extended columns aren't indexed.
+	entries, err := common.BodyEntries(io.TeeReader(r, h))
+	if err != nil {
+		return 0, err
+	}
+
+	body := bodyfile.NewReader(entries)
+	count := 0
+
+	var first uint64
+
+	for done := false; !done; {
+
+		// Commit the entries in batches so large body files don't build one huge transaction.
+		// The source is written with the last batch.
+		err = ix.db.Update(func(tx *bolt.Tx) error {
+
+			if first == 0 {
+				first = tx.Bucket(entriesBucket).Sequence() + 1
+			}
+
+			for i := 0; i < batchSize; i++ {
+				e, err := body.Read()
+				if err == io.EOF {
+					done = true
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if err := putEntry(tx, e); err != nil {
+					return err
+				}
+				count++
+			}
+
+			if !done {
+				return nil
+			}
+
+			key := h.Sum(nil)
+			if tx.Bucket(sourcesBucket).Get(key) != nil {
+				return ErrIndexed
+			}
+
+			data, err := json.Marshal(source{Name: name, Entries: count})
+			if err != nil {
+				return err
+			}
+
+			return tx.Bucket(sourcesBucket).Put(key, data)
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+
+		// Roll back the batches that were already committed.
+		if rollbackErr := ix.db.Update(func(tx *bolt.Tx) error { return removeEntries(tx, first) }); rollbackErr != nil {
+			return count, fmt.Errorf("%v, and the entries already added could not be removed: %v", err, rollbackErr)
+		}
+
+		return 0, err
+	}
+
+	return count, nil
+}
+
+/*
+Returns the entry stored under the given id.
+*/
+func getEntry(tx *bolt.Tx, id []byte) (*bodyfile.Entry, error) {
+
+	data := tx.Bucket(entriesBucket).Get(id)
+	if data == nil {
+		return nil, fmt.Errorf("entry %d is missing from the index", binary.BigEndian.Uint64(id))
+	}
+
+	var e bodyfile.Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+/*
+ForEach calls fn for every entry in the index.
+*/
+func (ix *Index) ForEach(fn func(e *bodyfile.Entry) error) error {
+
+	return ix.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(entriesBucket).ForEach(func(id, _ []byte) error {
+			e, err := getEntry(tx, id)
+			if err != nil {
+				return err
+			}
+			return fn(e)
+		})
+	})
+}
+
+/*
+Range calls fn once for every entry having a timestamp between from and to (inclusive).
+*/
+func (ix *Index) Range(from int64, to int64, fn func(e *bodyfile.Entry) error) error {
+
+	return ix.db.View(func(tx *bolt.Tx) error {
+
+		seen := make(map[uint64]bool)
+		c := tx.Bucket(timeBucket).Cursor()
+		end := timeKey(to, ^uint64(0))
+
+		for k, _ := c.Seek(timeKey(from, 0)); k != nil && bytes.Compare(k, end) <= 0; k, _ = c.Next() {
+
+			id := binary.BigEndian.Uint64(k[8:])
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			e, err := getEntry(tx, k[8:])
+			if err != nil {
+				return err
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+/*
+Returns the entries stored under value in a lookup bucket.
+*/
+func (ix *Index) lookup(bucket []byte, value string) ([]*bodyfile.Entry, error) {
+
+	var found []*bodyfile.Entry
+
+	err := ix.db.View(func(tx *bolt.Tx) error {
+
+		// Indexes created before the bucket was added don't have it.
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return fmt.Errorf("the index has no %s bucket, index the body files again", bucket)
+		}
+
+		prefix := append([]byte(value), 0)
+		c := b.Cursor()
+
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) && len(k) == len(prefix)+8; k, _ = c.Next() {
+			e, err := getEntry(tx, k[len(prefix):])
+			if err != nil {
+				return err
+			}
+			found = append(found, e)
+		}
+
+		return nil
+	})
+
+	return found, err
+}
+
+/*
+ByPath returns the entries with the given name.
+*/
+func (ix *Index) ByPath(name string) ([]*bodyfile.Entry, error) {
+	return ix.lookup(pathBucket, name)
+}
+
+/*
+ByHash returns the entries with the given MD5.
+*/
+func (ix *Index) ByHash(md5 string) ([]*bodyfile.Entry, error) {
+	return ix.lookup(hashBucket, md5)
+}
+
+/*
+IndexBody adds each body file to the index database, creating it if needed.
+*/
+func IndexBody(dbPath string, bodyFiles []string, identityFile string) {
+
+	ix, err := Open(dbPath, true)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer ix.Close()
+
+	for _, bodyFile := range bodyFiles {
+
+		var f io.ReadCloser
+
+		// Compressed and encrypted body files and packages are decoded.
+		if bodyFile == "-" {
+			f, err = common.DecodeFile(os.Stdin, identityFile)
+		} else {
+			f, err = common.OpenBody(bodyFile, identityFile)
+		}
+		if err != nil {
+			fmt.Printf("Could not open %s: %s\n", bodyFile, err)
+			continue
+		}
+
+		count, err := ix.Add(bodyFile, f)
+		f.Close()
+
+		// Indexing the same body file twice would duplicate its entries.
+		if errors.Is(err, ErrIndexed) {
+			fmt.Printf("Skipping %s, it is already in the index.\n", bodyFile)
+			continue
+		}
+
+		if err != nil {
+			fmt.Printf("Could not index %s: %s\n", bodyFile, err)
+			continue
+		}
+
+		fmt.Printf("Indexed %d entries from %s\n", count, bodyFile)
+	}
+}
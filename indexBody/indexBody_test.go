@@ -0,0 +1,112 @@
+package indexBody
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/airbus-cert/bodyfile"
+)
+
+const (
+	firstBody  = "0|/etc/passwd|1|33188|0|0|10|100|200|300|0\n0|/etc/group|2|33188|0|0|10|100|200|300|0\n"
+	secondBody = "0|/etc/passwd|1|33188|0|0|12|400|500|600|0\n"
+	hashedBody = "0cc175b9c0f1b6a831c399e269772661|/tmp/a|3|33188|0|0|1|100|200|300|0\n0cc175b9c0f1b6a831c399e269772661|/var/tmp/a|4|33188|0|0|1|100|200|300|0\n"
+)
+
+func openTest(t *testing.T) *Index {
+	ix, err := Open(filepath.Join(t.TempDir(), "test.db"), true)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { ix.Close() })
+	return ix
+}
+
+func countEntries(t *testing.T, ix *Index) int {
+	count := 0
+	if err := ix.ForEach(func(e *bodyfile.Entry) error { count++; return nil }); err != nil {
+		t.Fatalf("ForEach() error: %v", err)
+	}
+	return count
+}
+
+func TestAddSkipsSameContent(t *testing.T) {
+	ix := openTest(t)
+
+	if n, err := ix.Add("-", strings.NewReader(firstBody)); err != nil || n != 2 {
+		t.Fatalf("Add(first) = %d, %v, want 2", n, err)
+	}
+	if _, err := ix.Add("copy.body", strings.NewReader(firstBody)); !errors.Is(err, ErrIndexed) {
+		t.Errorf("Add(same content) error = %v, want ErrIndexed", err)
+	}
+	if n, err := ix.Add("-", strings.NewReader(secondBody)); err != nil || n != 1 {
+		t.Errorf("Add(other content from stdin) = %d, %v, want 1", n, err)
+	}
+
+	if got := countEntries(t, ix); got != 3 {
+		t.Errorf("index has %d entries, want 3", got)
+	}
+}
+
+func TestAddRollsBack(t *testing.T) {
+	defer func(size int) { batchSize = size }(batchSize)
+	batchSize = 1
+
+	ix := openTest(t)
+
+	// The first two entries are committed in their own batches before the bad line.
+	if _, err := ix.Add("bad.body", strings.NewReader(firstBody+"not a body line\n")); err == nil {
+		t.Fatal("Add(bad) didn't return an error")
+	}
+	if got := countEntries(t, ix); got != 0 {
+		t.Errorf("index has %d entries after a failed Add, want 0", got)
+	}
+	if entries, _ := ix.ByPath("/etc/passwd"); len(entries) != 0 {
+		t.Errorf("ByPath() found %d entries after a failed Add, want 0", len(entries))
+	}
+	if err := ix.Range(0, 1000, func(e *bodyfile.Entry) error {
+		t.Errorf("Range() found %s after a failed Add", e.Name)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range() error: %v", err)
+	}
+
+	// A failed body file can be indexed once fixed.
+	if n, err := ix.Add("bad.body", strings.NewReader(firstBody)); err != nil || n != 2 {
+		t.Errorf("Add(fixed) = %d, %v, want 2", n, err)
+	}
+}
+
+func TestLookups(t *testing.T) {
+	ix := openTest(t)
+
+	for _, body := range []string{firstBody, secondBody, hashedBody} {
+		if _, err := ix.Add("-", strings.NewReader(body)); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+
+	entries, err := ix.ByPath("/etc/passwd")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ByPath() = %d entries, %v, want 2", len(entries), err)
+	}
+
+	entries, err = ix.ByHash("0cc175b9c0f1b6a831c399e269772661")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ByHash() = %d entries, %v, want 2", len(entries), err)
+	}
+	if entries, _ := ix.ByHash("0"); len(entries) != 0 {
+		t.Errorf("ByHash(0) = %d entries, want the entries without an MD5 left out", len(entries))
+	}
+
+	var names []string
+	err = ix.Range(350, 450, func(e *bodyfile.Entry) error {
+		names = append(names, e.Name)
+		return nil
+	})
+	if err != nil || len(names) != 1 || names[0] != "/etc/passwd" {
+		t.Errorf("Range(350, 450) = %v, %v, want [/etc/passwd]", names, err)
+	}
+}
@@ -4,117 +4,207 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"time"
+	"strings"
 
-	"gobodyfile/createBody"
-	"gobodyfile/processBody"
+	"gobodyfile/common"
 )
 
+/*
+command is a subcommand of gobodyfile with its own options. legacy is the option that selected it
+before there were subcommands, which is still accepted in its place.
+*/
+type command struct {
+	name    string
+	legacy  string
+	summary string
+	run     func(args []string)
+}
+
+// Subcommands in the order they are listed.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"collect", "-body", "Collect the metadata of files into a body file.", runCollect},
+		{"timeline", "-process", "Show the timeline, file view, summary or pivot of a body file or index database.", runTimeline},
+		{"index", "-index", "Add body files to an index database.", runIndex},
+		{"diff", "-diff", "Compare two body files.", runDiff},
+		{"snapshots", "-serve-snapshots", "Take body file snapshots on an interval.", runSnapshots},
+		{"verify", "-verify-manifest", "Check the hashes of a collection manifest.", runVerify},
+		{"help", "", "Show the commands, or the options of a command.", runHelp},
+	}
+}
+
 /*
 Gathers information from the file's inode and write it to a file.
 */
 
 func main() {
 
-	// Be sure -body or -process was passed as os.Args[1].
+	// Be sure a command was passed as os.Args[1].
 	if len(os.Args) < 2 {
 
-		fmt.Println("Please provide -body or -process as the first argument.")
+		usage()
 		os.Exit(1)
+
 	}
 
-	// Check if the first flag value is "body."
-	if os.Args[1] == "-body" {
+	c := findCommand(os.Args[1])
+	if c == nil {
 
-		// Variable for the directory that will be searched.
-		var rootDir string
-		var outputFile string
-		var body bool
-		var sid bool
+		if arg := os.Args[1]; arg == "-h" || arg == "-help" || arg == "--help" {
+			usage()
+			return
+		}
 
-		// Pass the name of the directory via the commandline.
-		flag.StringVar(&rootDir, "directory", "", "Directory containing the files to collect metadata.")
-		flag.StringVar(&outputFile, "output", "", "Output file name")
-		flag.BoolVar(&body, "body", false, "Create Body file")
-		flag.BoolVar(&sid, "sid", false, "(Optional) Display the SID. Default will return the UID and GID.")
+		fmt.Printf("Unknown command %s.\n\n", os.Args[1])
+		usage()
+		os.Exit(1)
 
-		flag.Parse()
+	}
 
-		// Check if the root value is empty.
-		if rootDir == "" {
+	c.run(os.Args[2:])
 
-			fmt.Println("Directory not provided. Use -directory and provide the directory name.")
-			return
+}
+
+/*
+Returns the command with a name or legacy option, or nil when there is none.
+*/
+func findCommand(name string) *command {
 
+	for i, c := range commands {
+		if c.name == name || (c.legacy != "" && c.legacy == name) {
+			return &commands[i]
 		}
+	}
 
-		// Check if the outputFile value is empty.
-		if outputFile == "" {
+	return nil
+}
 
-			fmt.Println("Output file not provided. Use -output and provide the file's name.")
-			return
+/*
+Prints the commands.
+*/
+func usage() {
+
+	fmt.Printf("Usage: %s <command> [options] [arguments]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Printf("  %-10s %s\n", c.name, c.summary)
+	}
+
+	fmt.Printf(`
+Run "%s help <command>" to show the options of a command. The options before subcommands
+(-body, -process, -index, -diff, -serve-snapshots, -verify-manifest) still select them.
+
+Defaults for the options are read from the [<command>] table of the configuration file
+(%s, or -config) and from environment variables like
+GOBODYFILE_COLLECT_OUTPUT or GOBODYFILE_IDENTITY. Options on the command line win.
+`, os.Args[0], common.DefaultConfigFile())
+
+}
+
+/*
+Shows the options of a command.
+*/
+func runHelp(args []string) {
+
+	if len(args) == 0 {
+		usage()
+		return
+	}
+
+	c := findCommand(args[0])
+	if c == nil || c.name == "help" {
+		fmt.Printf("Unknown command %s.\n\n", args[0])
+		usage()
+		os.Exit(1)
+	}
+
+	c.run([]string{"-h"})
+
+}
+
+/*
+Returns the options of a command. arguments describes what comes after the options in the usage,
+and examples are shown before the options.
+*/
+func newFlagSet(name string, arguments string, examples string) *flag.FlagSet {
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.String("config", common.DefaultConfigFile(), "Configuration file with the defaults of each command in [<command>] tables and the collection profiles.")
+
+	fs.Usage = func() {
+
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s %s [options] %s\n", os.Args[0], name, arguments)
+		if examples != "" {
+			fmt.Fprintf(out, "\n%s\n", strings.TrimSpace(examples))
+		}
+
+		fmt.Fprintf(out, "\nOptions:\n")
+		fs.PrintDefaults()
+
+		fmt.Fprintf(out, "\nDefaults are read from the [%s] table of the configuration file and from %s\nenvironment variables. Options on the command line win.\n", name, common.EnvName(name, "<OPTION>"))
+	}
+
+	return fs
+}
 
+/*
+Parses the options of a command and returns its arguments. Options can come before or after the
+arguments, "--" ends them. Options that weren't given are then set from the environment and the
+configuration file.
+*/
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+
+	var positional []string
+
+	for {
+
+		fs.Parse(args)
+
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
 		}
 
-		// Create the body file.
-		createBody.CreateBody(rootDir, outputFile, sid)
-
-	} else if os.Args[1] == "-process" {
-
-		// Create flags for processing the body file.
-		var flagProcess = flag.Bool("process", false, "Process the body file.")
-		var strict = flag.Bool("strict", false, "Only show the entries matching the date restrictions")
-		var filter = flag.String("filter", "", "Event filter (e.g., \"hour > 12\", \"day == 19\", \"weekday == \\\"Monday\\\"\")")
-		var modifiedFilter = flag.String("modified", "", "Filter on modification time only (e.g., \"date > \\\"2025-06-17\\\"\")")
-		var accessFilter = flag.String("access", "", "Filter on access time only (e.g., \"date > \\\"2025-06-17\\\"\")")
-		var ctimeFilter = flag.String("ctime", "", "Filter on change time only (e.g., \"date > \\\"2025-06-17\\\"\")")
-
-		flag.Usage = func() {
-			usage := fmt.Sprintf(`Usage of %s:
-	%s [options] bodyfile.txt
-
-Filter examples:
-  -filter "hour > 12"     (files modified after noon)
-  -filter "hour < 6"      (files modified before 6 AM)
-  -filter "day == 19"     (files modified on the 19th)
-  -filter "weekday == \"Monday\"" (files modified on Monday)
-  -filter "hour >= 9 && hour <= 17" (files modified 9 AM to 5 PM)
-  -filter "date > "%s"" (files modified in last hour)
-  -filter "date > "2025-06-19 13:47:35"" (files modified after specific time)
-  -filter "date > "2025-06-19"" (files modified after specific date)
-  -filter "date > "2025/06/19 13:47:35"" (slash format also supported)
-
-Timestamp-specific filters (only show entries for the specified timestamp type):
-  -modified "date < "2025-06-17"" (filter on modification time only)
-  -access "date > "2025-06-19"" (filter on access time only)
-  -ctime "date == "2025-06-16"" (filter on change time only)
-
-Note: -filter checks ALL timestamp types (access, modification, change, creation).
-      -modified, -access, -ctime check ONLY the specified timestamp type.
-      Use -strict to show only matching timestamps instead of all timestamps for matching files.
-
-IMPORTANT: Both YYYY-MM-DD and YYYY/MM/DD formats are supported for date filters.
-For relative time, use 'date > "YYYY-MM-DD HH:MM:SS"' instead
-
-`, os.Args[0], os.Args[0], time.Now().Add(-1*time.Hour).Format("2006-01-02 15:04:05"))
-
-			fmt.Fprintf(flag.CommandLine.Output(), usage)
-			flag.PrintDefaults()
-			os.Exit(1)
+		// Everything after -- is an argument.
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
 		}
 
-		flag.Parse()
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	if err := common.SetFromEnv(fs, fs.Name(), os.LookupEnv); err != nil {
 
-		// Get the input file from the command line.
-		f := processBody.GetInput()
+		fmt.Printf("Invalid option in the environment: %v\n", err)
+		os.Exit(2)
 
-		// Process the body file.
-		processBody.ProcessBody(f, flagProcess, strict, filter, modifiedFilter, accessFilter, ctimeFilter)
+	}
 
-	} else {
+	config, err := common.LoadConfig(configFile(fs))
+	if err != nil {
 
-		fmt.Println("Please select the '-body' or '-process' option.")
+		fmt.Printf("Could not read the configuration: %v\n", err)
+		os.Exit(2)
 
 	}
 
+	if err := common.SetFromConfig(fs, fs.Name(), config); err != nil {
+
+		fmt.Printf("Invalid option in the configuration: %v\n", err)
+		os.Exit(2)
+
+	}
+
+	return positional
+}
+
+/*
+Returns the configuration file of a command.
+*/
+func configFile(fs *flag.FlagSet) string {
+	return fs.Lookup("config").Value.String()
 }
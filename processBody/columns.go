@@ -0,0 +1,83 @@
+package processBody
+
+import (
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
+)
+
+// Extended columns declared in the header of the body file, and their values by entry path.
+var bodyColumns []string
+var columnValues map[string]map[string]string
+
+/*
+Keeps the extended column values of an entry read from the body file.
+*/
+func recordColumns(name string, values map[string]string) {
+	columnValues[name] = values
+}
+
+/*
+Checks if the body file being processed has an extended column.
+*/
+func hasColumn(column string) bool {
+	return slices.Contains(bodyColumns, column)
+}
+
+/*
+Returns the value of an extended column of an entry, or an empty string when it wasn't collected.
+*/
+func entryColumn(e *bodyfile.Entry, column string) string {
+	return columnValues[e.Name][column]
+}
+
+/*
+Adds the filter variables of the extended columns. They are empty when the body file doesn't have
+the column:
+
+	type          type detected from the first bytes by collect -types (elf, pe, pdf, zip, text...)
+	ext_mismatch  true when the extension contradicts the type, like an invoice.pdf that is an ELF
+	entropy       bits per byte computed by collect -entropy, from 0 to 8, or -1 when it wasn't
+	ssdeep        fuzzy hash computed by collect -ssdeep, also compared with similar_to("<hash>", 80)
+*/
+func columnParameters(e *bodyfile.Entry, params govaluate.MapParameters) {
+
+	fileType := entryColumn(e, "type")
+
+	params["type"] = fileType
+	params["ext_mismatch"] = common.ExtensionMismatch(e.Name, fileType)
+	params["entropy"] = parseEntropy(entryColumn(e, "entropy"))
+	params["ssdeep"] = entryColumn(e, "ssdeep")
+}
+
+/*
+Parses the entropy column, -1 when it wasn't computed.
+*/
+func parseEntropy(value string) float64 {
+
+	entropy, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return -1
+	}
+
+	return entropy
+}
+
+/*
+Returns the detected type shown after an entry whose extension contradicts it, or an empty string.
+*/
+func typeLabel(e *bodyfile.Entry) string {
+
+	fileType := entryColumn(e, "type")
+	if !common.ExtensionMismatch(e.Name, fileType) {
+		return ""
+	}
+
+	return "[type: " + fileType + ", not " + strings.ToLower(path.Ext(e.Name)) + "]"
+}
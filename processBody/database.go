@@ -0,0 +1,221 @@
+package processBody
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/indexBody"
+)
+
+/*
+dateBounds returns the time range a filter is restricted to so only that part of the
+index has to be searched. The range is only used when every date comparison must hold,
+which means the filter can't contain || or a negation.
+*/
+func dateBounds(filter string) (int64, int64, bool) {
+
+	if strings.Contains(filter, "||") || strings.Contains(strings.ReplaceAll(filter, "!=", ""), "!") {
+		return 0, 0, false
+	}
+
+	from := int64(math.MinInt64)
+	to := int64(math.MaxInt64)
+
+	re := regexp.MustCompile(`\bdate\s*(>=|<=|==|>|<)\s*(-?[0-9]+)`)
+	matches := re.FindAllStringSubmatch(filter, -1)
+	if len(matches) == 0 {
+		return 0, 0, false
+	}
+
+	for _, m := range matches {
+		value, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+
+		switch m[1] {
+		case ">":
+			from = max(from, value+1)
+		case ">=":
+			from = max(from, value)
+		case "<":
+			to = min(to, value-1)
+		case "<=":
+			to = min(to, value)
+		case "==":
+			from = max(from, value)
+			to = min(to, value)
+		}
+	}
+
+	return from, to, true
+}
+
+/*
+pathBound returns the path a filter is restricted to so only its entries have to be read from the
+index. Like dateBounds, the filter can't contain || or a negation. Quoted paths with a backslash
+are left to the filter, and so are paths normalized with -normalize-paths.
+*/
+func pathBound(filter string) (string, bool) {
+
+	if normalizePaths || strings.Contains(filter, "||") || strings.Contains(strings.ReplaceAll(filter, "!=", ""), "!") {
+		return "", false
+	}
+
+	re := regexp.MustCompile(`\b(?:path|p)\s*==\s*(?:"([^"\\]*)"|'([^'\\]*)')`)
+	m := re.FindStringSubmatch(filter)
+	if m == nil {
+		return "", false
+	}
+
+	return m[1] + m[2], true
+}
+
+/*
+md5Bound returns the MD5 a filter is restricted to so only its entries have to be read from the
+index. Like pathBound, the filter can't contain || or a negation.
+*/
+func md5Bound(filter string) (string, bool) {
+
+	if strings.Contains(filter, "||") || strings.Contains(strings.ReplaceAll(filter, "!=", ""), "!") {
+		return "", false
+	}
+
+	re := regexp.MustCompile(`\bmd5\s*==\s*(?:"([0-9a-fA-F]{32})"|'([0-9a-fA-F]{32})')`)
+	m := re.FindStringSubmatch(filter)
+	if m == nil {
+		return "", false
+	}
+
+	return m[1] + m[2], true
+}
+
+/*
+timestampedEntries returns the timeline entries for a matching entry the same way bodyfile.Slurp does.
+*/
+func timestampedEntries(e *bodyfile.Entry, strict bool) []bodyfile.TimeStampedEntry {
+
+	var tsEntries []bodyfile.TimeStampedEntry
+
+	events := indexBody.Events(e)
+
+	// Keep the order of bodyfile.Slurp so entries with the same time are always listed the same way.
+	for _, kind := range []int{bodyfile.AccessTime, bodyfile.ModificationTime, bodyfile.ChangeTime, bodyfile.CreationTime} {
+		t, ok := events[kind]
+		if !ok {
+			continue
+		}
+		if (!strict && t.Unix() >= 0) || (strict && e.MatchingTimestamp&kind != 0) {
+			tsEntries = append(tsEntries, bodyfile.TimeStampedEntry{Time: t, Entry: e})
+		}
+	}
+
+	return tsEntries
+}
+
+/*
+indexTimeline returns the sorted timeline entries of the index matching the filter.
+*/
+func indexTimeline(dbPath string, finalFilter string, strict bool) ([]bodyfile.TimeStampedEntry, error) {
+
+	ix, err := indexBody.Open(dbPath, false)
+	if err != nil {
+		return nil, err
+	}
+	defer ix.Close()
+
+	ef, err := newEntryFilter(finalFilter)
+	if err != nil {
+		return nil, fmt.Errorf("could not add filter: %s", err)
+	}
+
+	var timeline []bodyfile.TimeStampedEntry
+	read, hashed := 0, 0
+
+	collect := func(e *bodyfile.Entry) error {
+		read++
+		if e.MD5 != "" && e.MD5 != "0" {
+			hashed++
+		}
+		matched, err := ef.Match(e)
+		if err != nil {
+			return err
+		}
+		if matched {
+			timeline = append(timeline, timestampedEntries(e, strict)...)
+		}
+		return nil
+	}
+
+	// Only read the entries of the filter's path or MD5, or the part of the index covered by its
+	// dates. Known bad files are alerted on whatever the filter, so every entry is read for them.
+	var entries []*bodyfile.Entry
+	var bound bool
+
+	if name, ok := pathBound(finalFilter); ok && !alertKnownBad {
+		entries, err = ix.ByPath(name)
+		bound = true
+	} else if md5, ok := md5Bound(finalFilter); ok && !alertKnownBad {
+		entries, err = ix.ByHash(md5)
+		bound = true
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bound {
+		for _, e := range entries {
+			if err = collect(e); err != nil {
+				return nil, err
+			}
+		}
+	} else if from, to, ok := dateBounds(finalFilter); ok && !alertKnownBad {
+		err = ix.Range(from, to, collect)
+	} else {
+		err = ix.ForEach(collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash sets are matched against the MD5s, entries indexed without them are all unknown.
+	if hashSetsLoaded() && read > 0 && hashed == 0 {
+		fmt.Fprintln(os.Stderr, "The index has no MD5s to match the hash sets with, index body files collected with -md5.")
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+
+	return timeline, nil
+}
+
+/* ProcessIndex runs the filters against an index database created with -index.
+ */
+func ProcessIndex(dbPath string, strict *bool, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
+
+	finalFilter, timestampTypes := selectFilter(filter, modifiedFilter, accessFilter, ctimeFilter)
+
+	timeline, err := indexTimeline(dbPath, finalFilter, *strict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the index: %s\n", err)
+		os.Exit(3)
+	}
+
+	// If no results found and a filter was used, show helpful message.
+	if len(timeline) == 0 && finalFilter != "" {
+		showFilterHelp(*filter)
+		return
+	}
+
+	for i := range timeline {
+		printEntry(&timeline[i], timestampTypes)
+	}
+}
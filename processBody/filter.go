@@ -0,0 +1,192 @@
+package processBody
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Knetic/govaluate"
+	"github.com/airbus-cert/bodyfile"
+)
+
+/*
+entryFilter evaluates a filter on the entries. It has the same variables as the bodyfile reader's
+filter (path, hour, min, day, date, weekday and their short forms), plus the owners, the extended
+columns of the body file, the hash sets and the ones from the extended data loaded for -process,
+and marks the matching timestamps the same way.
+*/
+type entryFilter struct {
+	expression *govaluate.EvaluableExpression
+
+	// Entry being evaluated and the scores of its similar_to calls.
+	current *bodyfile.Entry
+	scores  map[string]int
+}
+
+/*
+newEntryFilter parses the filter. An empty filter matches every entry.
+*/
+func newEntryFilter(filter string) (*entryFilter, error) {
+
+	if filter == "" {
+		return &entryFilter{}, nil
+	}
+
+	ef := &entryFilter{}
+
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(filter, map[string]govaluate.ExpressionFunction{
+		"similar_to": ef.similarTo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ef.expression = expression
+
+	return ef, nil
+}
+
+/*
+entryParameters returns the variables of the entry that don't depend on the timestamp.
+*/
+func entryParameters(e *bodyfile.Entry) govaluate.MapParameters {
+
+	params := govaluate.MapParameters{
+		"path": e.Name,
+		"p":    e.Name,
+		"md5":  e.MD5,
+	}
+
+	ownerParameters(e, params)
+	xattrParameters(e, params)
+	columnParameters(e, params)
+	knownParameters(e, params)
+
+	return params
+}
+
+/*
+setTimeParameters sets the variables of the timestamp being evaluated. They are in the timezone
+the times are shown in, so hour == 13 matches the rows shown at 13:00.
+*/
+func setTimeParameters(params govaluate.MapParameters, t time.Time) {
+
+	t = t.In(displayLocation)
+
+	params["hour"] = t.Hour()
+	params["min"] = t.Minute()
+	params["day"] = t.Day()
+	params["date"] = t.Unix()
+	params["weekday"] = t.Weekday().String()
+
+	params["h"] = t.Hour()
+	params["m"] = t.Minute()
+	params["D"] = t.Day()
+	params["d"] = t.Unix()
+	params["w"] = t.Weekday().String()
+}
+
+/*
+Match checks if the filter matches any timestamp of the entry and records the ones that did in
+MatchingTimestamp.
+*/
+func (ef *entryFilter) Match(e *bodyfile.Entry) (bool, error) {
+
+	normalizeEntry(e)
+
+	// Known good files are left out before the filter, known bad ones are alerted on regardless of it.
+	if skipKnown(e) {
+		return false, nil
+	}
+
+	if ef.expression == nil {
+		return true, nil
+	}
+
+	params := entryParameters(e)
+	matched := false
+
+	ef.current = e
+	ef.scores = make(map[string]int)
+
+	// Same order as bodyfile.Reader.Match.
+	timestamps := []struct {
+		kind int
+		t    time.Time
+	}{
+		{bodyfile.AccessTime, e.AccessTime},
+		{bodyfile.ModificationTime, e.ModificationTime},
+		{bodyfile.CreationTime, e.CreationTime},
+		{bodyfile.ChangeTime, e.ChangeTime},
+	}
+
+	for _, ts := range timestamps {
+
+		setTimeParameters(params, ts.t)
+
+		decision, err := ef.expression.Eval(params)
+		if err != nil {
+			return false, fmt.Errorf("Could not evaluate expression: %s", err)
+		}
+
+		ok, isBool := decision.(bool)
+		if !isBool {
+			return false, fmt.Errorf("the filter is not a condition: %v", ef.expression)
+		}
+
+		if ok {
+			e.MatchingTimestamp |= ts.kind
+			matched = true
+		}
+	}
+
+	return matched, nil
+}
+
+/*
+Read returns the next entry of the body file matching the filter.
+*/
+func (ef *entryFilter) Read(body *bodyfile.Reader) (*bodyfile.Entry, error) {
+
+	for {
+		e, err := body.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		matched, err := ef.Match(e)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			return e, nil
+		}
+	}
+}
+
+/*
+Slurp returns the sorted timeline of the entries matching the filter, the same way
+bodyfile.Reader.Slurp does.
+*/
+func (ef *entryFilter) Slurp(body *bodyfile.Reader, strict bool) ([]bodyfile.TimeStampedEntry, error) {
+
+	var timeline []bodyfile.TimeStampedEntry
+
+	for {
+		e, err := ef.Read(body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error while reading file: %s", err)
+		}
+
+		timeline = append(timeline, timestampedEntries(e, strict)...)
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+
+	return timeline, nil
+}
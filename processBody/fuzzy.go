@@ -0,0 +1,195 @@
+package processBody
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/glaslos/ssdeep"
+)
+
+/*
+similarTo is the similar_to("<ssdeep hash>", <score>) filter function. It is true when the ssdeep
+hash of the entry matches the hash with a score of at least the given one, from 0 to 100. Entries
+without an ssdeep hash never match.
+*/
+func (ef *entryFilter) similarTo(args ...interface{}) (interface{}, error) {
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("similar_to takes an ssdeep hash and a score, like similar_to(\"96:abc...:def...\", 80)")
+	}
+
+	hash, okHash := args[0].(string)
+	minScore, okScore := args[1].(float64)
+	if !okHash || !okScore {
+		return nil, fmt.Errorf("similar_to takes an ssdeep hash and a score, like similar_to(\"96:abc...:def...\", 80)")
+	}
+
+	entryHash := entryColumn(ef.current, "ssdeep")
+	if entryHash == "" {
+		return false, nil
+	}
+
+	// The filter is evaluated once per timestamp of the entry.
+	score, ok := ef.scores[hash]
+	if !ok {
+		var err error
+		if score, err = ssdeep.Distance(entryHash, hash); err != nil {
+			return nil, fmt.Errorf("similar_to: %v", err)
+		}
+		ef.scores[hash] = score
+	}
+
+	return float64(score) >= minScore, nil
+}
+
+// Length of the substrings two ssdeep hashes must share to get a score, as ssdeep requires.
+const fuzzyGram = 7
+
+/*
+Returns the block sized substrings of an ssdeep hash used to find the hashes it can match. Hashes
+are only compared with the same or twice the block size, the second part of a hash is for twice
+its block size.
+*/
+func fuzzyGrams(hash string) []string {
+
+	parts := strings.Split(hash, ":")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	blockSize, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil
+	}
+
+	var grams []string
+	for i, part := range parts[1:] {
+		size := strconv.Itoa(blockSize << i)
+		for j := 0; j+fuzzyGram <= len(part); j++ {
+			grams = append(grams, size+":"+part[j:j+fuzzyGram])
+		}
+	}
+
+	return grams
+}
+
+/*
+Groups the files whose ssdeep hashes match with at least Similarity, across all the body files.
+Files are in the same group when they are similar to one of its files. Identical hashes are compared
+once, and only hashes sharing a substring of their block size are scored.
+*/
+func reportSimilar(entries []reportEntry, opts ReportOptions) {
+
+	// Files by hash.
+	var hashes []string
+	files := make(map[string][]reportEntry)
+
+	for _, e := range entries {
+		hash := e.columns["ssdeep"]
+		if hash == "" {
+			continue
+		}
+		if _, ok := files[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+		files[hash] = append(files[hash], e)
+	}
+
+	// Union-find of the hashes.
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	seen := make(map[string][]int)
+	for i, hash := range hashes {
+
+		compared := make(map[int]bool)
+		for _, gram := range fuzzyGrams(hash) {
+			for _, j := range seen[gram] {
+				if compared[j] || find(i) == find(j) {
+					continue
+				}
+				compared[j] = true
+				if score, err := ssdeep.Distance(hash, hashes[j]); err == nil && score >= opts.Similarity {
+					parent[find(i)] = find(j)
+				}
+			}
+		}
+
+		for _, gram := range fuzzyGrams(hash) {
+			if list := seen[gram]; len(list) == 0 || list[len(list)-1] != i {
+				seen[gram] = append(list, i)
+			}
+		}
+	}
+
+	groups := make(map[int][]string)
+	for i, hash := range hashes {
+		groups[find(i)] = append(groups[find(i)], hash)
+	}
+
+	type group struct {
+		hashes []string
+		files  []reportEntry
+	}
+
+	var similar []group
+	for _, members := range groups {
+		g := group{hashes: members}
+		for _, hash := range members {
+			g.files = append(g.files, files[hash]...)
+		}
+		if len(g.files) > 1 {
+			similar = append(similar, g)
+		}
+	}
+
+	if len(similar) == 0 {
+		fmt.Printf("No files are similar with a score of %d or more.\n", opts.Similarity)
+		return
+	}
+
+	sort.Slice(similar, func(i, j int) bool {
+		if len(similar[i].files) != len(similar[j].files) {
+			return len(similar[i].files) > len(similar[j].files)
+		}
+		return similar[i].files[0].label() < similar[j].files[0].label()
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for n, g := range similar {
+
+		sort.Slice(g.files, func(i, j int) bool { return g.files[i].label() < g.files[j].label() })
+
+		sources := make(map[string]bool)
+		for _, e := range g.files {
+			sources[e.source] = true
+		}
+
+		fmt.Fprintf(w, "Group %d: %d files, %d distinct hashes, in %d body files\n", n+1, len(g.files), len(g.hashes), len(sources))
+
+		// Scores are shown against the first file of the group.
+		first := g.files[0].columns["ssdeep"]
+		for _, e := range g.files {
+			score, _ := ssdeep.Distance(first, e.columns["ssdeep"])
+			fmt.Fprintf(w, "  %d\t%s\t%d\t%s\t%s\n", score, e.ModificationTime.In(displayLocation).Format("2006-01-02 15:04:05"), e.Size, e.columns["ssdeep"], e.label())
+		}
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d groups of similar files with a score of %d or more\n", len(similar), opts.Similarity)
+}
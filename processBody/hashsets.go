@@ -0,0 +1,138 @@
+package processBody
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Knetic/govaluate"
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
+)
+
+// Hash sets of known good files, like NSRL, and of known bad files.
+var goodSets, badSets []*common.HashSet
+
+// Hide the known good files, and print an alert for each known bad one.
+var hideKnownGood, alertKnownBad bool
+
+// Entries already alerted on, by path and MD5, since an entry can be read more than once.
+var alerted = make(map[string]bool)
+
+/*
+LoadHashSet reads a hash set of known good or known bad files for -known-good and -known-bad.
+*/
+func LoadHashSet(path string, bad bool) error {
+
+	set, err := common.LoadHashSet(path)
+	if err != nil {
+		return err
+	}
+
+	kind := "known good"
+	if bad {
+		kind = "known bad"
+		badSets = append(badSets, set)
+	} else {
+		goodSets = append(goodSets, set)
+	}
+
+	fmt.Fprintf(os.Stderr, "Loaded %d %s hashes from %s\n", set.Len(), kind, path)
+
+	return nil
+}
+
+/*
+HideKnownGood leaves the files of the known good hash sets out, so the timeline isn't dominated by
+operating system and package files.
+*/
+func HideKnownGood() {
+	hideKnownGood = true
+}
+
+/*
+AlertKnownBad prints an alert on stderr for each file of the known bad hash sets, whether the filter
+matches it or not.
+*/
+func AlertKnownBad() {
+	alertKnownBad = true
+}
+
+/*
+Checks if hash sets were loaded.
+*/
+func hashSetsLoaded() bool {
+	return len(goodSets) > 0 || len(badSets) > 0
+}
+
+/*
+Returns whether an entry is a known good, known bad or unknown file, and the hash set it is in.
+Known bad wins when the file is in both.
+*/
+func knownStatus(e *bodyfile.Entry) (string, string) {
+
+	if e.MD5 == "" || e.MD5 == "0" {
+		return "unknown", ""
+	}
+
+	for _, set := range badSets {
+		if set.Contains(e.MD5) {
+			return "bad", set.Name
+		}
+	}
+
+	for _, set := range goodSets {
+		if set.Contains(e.MD5) {
+			return "good", set.Name
+		}
+	}
+
+	return "unknown", ""
+}
+
+/*
+Alerts on a known bad entry and checks if it is a known good one to hide.
+*/
+func skipKnown(e *bodyfile.Entry) bool {
+
+	if !hashSetsLoaded() {
+		return false
+	}
+
+	status, set := knownStatus(e)
+
+	if status == "bad" && alertKnownBad && !alerted[e.Name+"|"+e.MD5] {
+		alerted[e.Name+"|"+e.MD5] = true
+		fmt.Fprintf(os.Stderr, "ALERT: known bad file %s (MD5 %s in %s)\n", e.Name, e.MD5, set)
+	}
+
+	return status == "good" && hideKnownGood
+}
+
+/*
+Adds the filter variables of the hash sets:
+
+	known    good, bad or unknown, always unknown for entries without an MD5
+	hashset  name of the hash set the file is in, empty when it is unknown
+*/
+func knownParameters(e *bodyfile.Entry, params govaluate.MapParameters) {
+
+	params["known"], params["hashset"] = knownStatus(e)
+}
+
+/*
+Returns the hash set shown after a known entry, or an empty string.
+*/
+func knownLabel(e *bodyfile.Entry) string {
+
+	if !hashSetsLoaded() {
+		return ""
+	}
+
+	status, set := knownStatus(e)
+	if status == "unknown" {
+		return ""
+	}
+
+	return "[known " + status + ": " + set + "]"
+}
@@ -0,0 +1,105 @@
+package processBody
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gobodyfile/common"
+)
+
+// Timezone the times are shown in. The body file timestamps are parsed as UTC.
+var displayLocation = time.UTC
+
+/*
+ReadInput decodes a compressed or encrypted body file, or the body file of a package, reads its
+header, labels the source on stderr and sets the display timezone. The returned reader continues
+with the entries.
+*/
+func ReadInput(f *os.File, tz string, identityFile string) io.Reader {
+
+	decoded, err := common.DecodeFile(f, identityFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the body file: %s\n", err)
+		os.Exit(1)
+	}
+
+	loadPackagedXattrs(f)
+	loadPackagedOwners(f)
+
+	header, r, err := common.ReadHeader(decoded)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the body file header: %s\n", err)
+		os.Exit(1)
+	}
+
+	if header != nil {
+		label := fmt.Sprintf("Source: %s on %s, collected %s", header.Root, header.Host, header.Collected.Format("2006-01-02 15:04:05 MST"))
+		if len(header.Extensions) > 0 {
+			label += " (" + strings.Join(header.Extensions, ", ") + ")"
+		}
+		if header.Profile != "" {
+			label += ", profile " + header.Profile
+		}
+		fmt.Fprintln(os.Stderr, label)
+
+		// Files left out of the collection won't show up in the timeline.
+		if header.Scope != "" {
+			fmt.Fprintf(os.Stderr, "Collected only: %s\n", header.Scope)
+		}
+	}
+
+	// Hash sets are matched against the MD5s, a body file without them would all be unknown.
+	if hashSetsLoaded() && header != nil && !header.Has("md5") {
+		fmt.Fprintln(os.Stderr, "The body file has no MD5s to match the hash sets with, collect it with -md5.")
+		os.Exit(1)
+	}
+
+	UseTimezone(tz, header)
+
+	if normalizePaths {
+		r = slashReader{r}
+	}
+
+	// The extended columns are kept by path since the body file reader only knows the 11 fields.
+	bodyColumns, columnValues = nil, nil
+	if header != nil && len(header.Columns) > 0 {
+		bodyColumns = header.Columns
+		columnValues = make(map[string]map[string]string)
+		r = common.StripColumns(r, header.Columns, recordColumns)
+	}
+
+	return r
+}
+
+/*
+UseTimezone sets the timezone the timestamps are shown in. tz is "local" for this host's timezone,
+"UTC" or an IANA name like "Europe/Paris". When tz is empty, the timezone of the collection host
+from the header is used, if there is one. Filters and -pivot dates are read in the same timezone.
+*/
+func UseTimezone(tz string, header *common.Header) {
+
+	switch {
+	case strings.EqualFold(tz, "local"):
+		displayLocation = time.Local
+
+	case tz != "":
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown timezone %s: %s\n", tz, err)
+			os.Exit(2)
+		}
+		displayLocation = loc
+
+	case header != nil && header.Timezone != "":
+		loc, err := header.Location()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring the header timezone: %s\n", err)
+			return
+		}
+		displayLocation = loc
+		fmt.Fprintf(os.Stderr, "Showing times and reading filter dates in the collection timezone %s (use -tz to change it)\n", header.Timezone)
+	}
+}
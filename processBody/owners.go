@@ -0,0 +1,118 @@
+package processBody
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Knetic/govaluate"
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
+)
+
+// Account and group names of the UIDs and GIDs, nil unless owners are resolved.
+var owners *common.Owners
+
+/*
+LoadOwners reads the account and group names from a root directory ("/" for this system), an
+owners sidecar written by -body -owners, or a package holding one.
+*/
+func LoadOwners(source string) error {
+
+	o, err := common.LoadOwners(source)
+	if err != nil {
+		return err
+	}
+
+	owners = o
+
+	return nil
+}
+
+/*
+Loads the owners sidecar of a package being processed when no other source was given. The
+collection host's accounts are the right ones for its UIDs.
+*/
+func loadPackagedOwners(f *os.File) {
+
+	if owners != nil {
+		return
+	}
+
+	packaged, err := common.PackageFile(f, ".owners.json")
+	if err != nil {
+		return
+	}
+	defer packaged.Close()
+
+	if owners, err = common.ReadOwners(packaged); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the owners sidecar of the package: %s\n", err)
+	}
+}
+
+/*
+Returns the account name of the entry's owner, or the UID when it is unknown.
+*/
+func ownerName(e *bodyfile.Entry) string {
+
+	if owners != nil {
+		if name := owners.User(e.UID); name != "" {
+			return name
+		}
+	}
+
+	return strconv.Itoa(e.UID)
+}
+
+/*
+Returns the name of the entry's group, or the GID when it is unknown.
+*/
+func groupName(e *bodyfile.Entry) string {
+
+	if owners != nil {
+		if name := owners.Group(e.GID); name != "" {
+			return name
+		}
+	}
+
+	return strconv.Itoa(e.GID)
+}
+
+/*
+Returns an ID with its name as uid(name), or only the ID when the name is unknown.
+*/
+func formatID(id int, name string) string {
+
+	if owners == nil || name == strconv.Itoa(id) {
+		return strconv.Itoa(id)
+	}
+
+	return fmt.Sprintf("%d(%s)", id, name)
+}
+
+/*
+Adds the owner filter variables:
+
+	uid, gid      numeric IDs
+	owner, group  account and group names, or the ID as a string when the name is unknown
+*/
+func ownerParameters(e *bodyfile.Entry, params govaluate.MapParameters) {
+
+	params["uid"] = e.UID
+	params["gid"] = e.GID
+	params["owner"] = ownerName(e)
+	params["group"] = groupName(e)
+}
+
+/*
+Returns the owner shown after an entry when owners are resolved.
+*/
+func ownerLabel(e *bodyfile.Entry) string {
+
+	if owners == nil {
+		return ""
+	}
+
+	return "[" + formatID(e.UID, ownerName(e)) + ":" + formatID(e.GID, groupName(e)) + "]"
+}
@@ -0,0 +1,52 @@
+package processBody
+
+import (
+	"io"
+	"strings"
+
+	"github.com/airbus-cert/bodyfile"
+)
+
+// Backslashes in names are read as slashes, set with -normalize-paths.
+var normalizePaths bool
+
+/*
+NormalizePaths reads the backslashes in names as slashes, so Windows body files can be filtered
+with the same paths as the others.
+*/
+func NormalizePaths() {
+	normalizePaths = true
+}
+
+/*
+slashReader replaces the backslashes of a body file with slashes. Only the names can hold a
+backslash.
+*/
+type slashReader struct {
+	r io.Reader
+}
+
+/*
+Reads from the body file, replacing the backslashes.
+*/
+func (s slashReader) Read(p []byte) (int, error) {
+
+	n, err := s.r.Read(p)
+	for i := range p[:n] {
+		if p[i] == '\\' {
+			p[i] = '/'
+		}
+	}
+
+	return n, err
+}
+
+/*
+Normalizes the name of an entry read from an index database.
+*/
+func normalizeEntry(e *bodyfile.Entry) {
+
+	if normalizePaths {
+		e.Name = strings.ReplaceAll(e.Name, `\`, "/")
+	}
+}
@@ -0,0 +1,271 @@
+package processBody
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airbus-cert/bodyfile"
+	"github.com/mattn/go-isatty"
+
+	"gobodyfile/indexBody"
+)
+
+/*
+pivotAnchor is the event the pivot window is centered on. The entry is nil when the
+pivot is an explicit time.
+*/
+type pivotAnchor struct {
+	time  time.Time
+	entry *bodyfile.Entry
+}
+
+/*
+isAnchor checks if the timeline entry is the anchor event.
+*/
+func (a *pivotAnchor) isAnchor(tsEntry *bodyfile.TimeStampedEntry) bool {
+
+	if a.entry == nil || !tsEntry.Time.Equal(a.time) {
+		return false
+	}
+
+	return tsEntry.Entry.Name == a.entry.Name && tsEntry.Entry.Inode == a.entry.Inode
+}
+
+/*
+parsePivotTime returns the time when the pivot is a date rather than a path or inode.
+*/
+func parsePivotTime(pivot string) (time.Time, bool) {
+
+	// Paths never parse as a date, even after converting their slashes.
+	timestamp, err := parseHumanDate(strings.ReplaceAll(pivot, "/", "-"))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(timestamp, 0).UTC(), true
+}
+
+/*
+pivotMatches checks if an entry is the one the pivot refers to. An inode is given as inode:<number>.
+*/
+func pivotMatches(pivot string, e *bodyfile.Entry) bool {
+
+	if inode, found := strings.CutPrefix(pivot, "inode:"); found {
+		n, err := strconv.Atoi(inode)
+		return err == nil && e.Inode == n
+	}
+
+	return e.Name == pivot
+}
+
+/*
+findAnchor returns the anchor for the pivot from the candidate entries using the timestamp of the given MACB type.
+The earliest timestamp is used when several entries match.
+*/
+func findAnchor(pivot string, pivotType string, entries []*bodyfile.Entry) (*pivotAnchor, int, error) {
+
+	var anchor *pivotAnchor
+	found := 0
+
+	for _, e := range entries {
+
+		if !pivotMatches(pivot, e) {
+			continue
+		}
+		found++
+
+		t := macbTime(e, pivotType)
+		if anchor == nil || t.Before(anchor.time) {
+			anchor = &pivotAnchor{time: t, entry: e}
+		}
+	}
+
+	if anchor == nil {
+		return nil, 0, fmt.Errorf("no entry found for %s", pivot)
+	}
+
+	return anchor, found, nil
+}
+
+/*
+formatOffset returns the offset from the anchor as T+hh:mm:ss.
+*/
+func formatOffset(d time.Duration) string {
+
+	sign := "+"
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	seconds := int64(d / time.Second)
+
+	return fmt.Sprintf("T%s%02d:%02d:%02d", sign, seconds/3600, seconds/60%60, seconds%60)
+}
+
+/*
+pivotCandidates returns the anchor and the entries that may have events within the window.
+With an index, only the entries for the pivot and the window's time range are read.
+*/
+func pivotCandidates(f io.Reader, dbPath string, pivot string, pivotType string, window time.Duration) (*pivotAnchor, []*bodyfile.Entry, error) {
+
+	at, isTime := parsePivotTime(pivot)
+
+	if dbPath != "" {
+
+		ix, err := indexBody.Open(dbPath, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer ix.Close()
+
+		anchor := &pivotAnchor{time: at}
+
+		if !isTime {
+
+			var matches []*bodyfile.Entry
+
+			if strings.HasPrefix(pivot, "inode:") {
+				err = ix.ForEach(func(e *bodyfile.Entry) error {
+					if pivotMatches(pivot, e) {
+						matches = append(matches, e)
+					}
+					return nil
+				})
+			} else {
+				matches, err = ix.ByPath(pivot)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+
+			var found int
+			anchor, found, err = findAnchor(pivot, pivotType, matches)
+			if err != nil {
+				return nil, nil, err
+			}
+			if found > 1 {
+				fmt.Fprintf(os.Stderr, "%d entries match %s, using the earliest one.\n", found, pivot)
+			}
+		}
+
+		var entries []*bodyfile.Entry
+		err = ix.Range(anchor.time.Add(-window).Unix(), anchor.time.Add(window).Unix(), func(e *bodyfile.Entry) error {
+			entries = append(entries, e)
+			return nil
+		})
+
+		return anchor, entries, err
+	}
+
+	// The body file can only be read once, so keep every entry to find the anchor in.
+	var entries []*bodyfile.Entry
+	body := bodyfile.NewReader(f)
+	for {
+		e, err := body.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if isTime {
+		return &pivotAnchor{time: at}, entries, nil
+	}
+
+	anchor, found, err := findAnchor(pivot, pivotType, entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	if found > 1 {
+		fmt.Fprintf(os.Stderr, "%d entries match %s, using the earliest one.\n", found, pivot)
+	}
+
+	return anchor, entries, nil
+}
+
+/* ProcessPivot shows the events within the window around an anchor event. The pivot is a path,
+ * an inode as inode:<number>, or a date.
+ */
+func ProcessPivot(f io.Reader, dbPath string, pivot string, pivotType string, window time.Duration, strict *bool, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
+
+	if _, ok := macbNames[pivotType]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown pivot timestamp: %s (use m, a, c or b)\n", pivotType)
+		os.Exit(2)
+	}
+
+	finalFilter, timestampTypes := selectFilter(filter, modifiedFilter, accessFilter, ctimeFilter)
+
+	ef, err := newEntryFilter(finalFilter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not add filter: %s", err)
+		os.Exit(2)
+	}
+
+	anchor, entries, err := pivotCandidates(f, dbPath, pivot, pivotType, window)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not find the pivot: %s\n", err)
+		os.Exit(3)
+	}
+
+	from := anchor.time.Add(-window)
+	to := anchor.time.Add(window)
+
+	var timeline []bodyfile.TimeStampedEntry
+
+	for _, e := range entries {
+
+		matched, err := ef.Match(e)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not evaluate the filter: %s", err)
+			os.Exit(3)
+		}
+
+		for kind, t := range indexBody.Events(e) {
+
+			if t.Before(from) || t.After(to) {
+				continue
+			}
+
+			tsEntry := bodyfile.TimeStampedEntry{Time: t, Entry: e}
+
+			// The anchor is always shown even when the filter excludes it.
+			show := matched && (!*strict || e.MatchingTimestamp&kind != 0) && showTimestamp(&tsEntry, timestampTypes)
+			if show || anchor.isAnchor(&tsEntry) {
+				timeline = append(timeline, tsEntry)
+			}
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+
+	// Highlight the anchor in bold on a terminal.
+	highlight, reset := "", ""
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		highlight, reset = "\033[1m", "\033[0m"
+	}
+
+	fmt.Printf("Pivot on %s at %s, window +/- %s\n\n", pivot, anchor.time.In(displayLocation).Format("2006-01-02 15:04:05"), window)
+
+	for i := range timeline {
+
+		tsEntry := &timeline[i]
+		line := fmt.Sprintf("%s %s", formatOffset(tsEntry.Time.Sub(anchor.time)), formatEntry(tsEntry))
+
+		if anchor.isAnchor(tsEntry) {
+			fmt.Printf("%s=> %s%s\n", highlight, line, reset)
+		} else {
+			fmt.Printf("   %s\n", line)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package processBody
 
 import (
-	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -14,7 +13,8 @@ import (
 )
 
 /*
-parseHumanDate converts human-readable date formats to Unix timestamp.
+parseHumanDate converts human-readable date formats to Unix timestamp. Dates are read in the
+timezone the times are shown in.
 */
 func parseHumanDate(dateStr string) (int64, error) {
 
@@ -28,7 +28,7 @@ func parseHumanDate(dateStr string) (int64, error) {
 		"2006-01-02",
 	}
 	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
+		if t, err := time.ParseInLocation(format, dateStr, displayLocation); err == nil {
 			return t.Unix(), nil
 		}
 	}
@@ -39,11 +39,8 @@ func parseHumanDate(dateStr string) (int64, error) {
  */
 func processFilter(filter string) (string, error) {
 
-	// Convert slashes to dashes for user convenience.
-	filter = strings.ReplaceAll(filter, "/", "-")
-
-	// Match dash format only.
-	re := regexp.MustCompile(`date(\s*)([<>=!]+)\s*["']?([0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:\s+[0-9]{1,2}:[0-9]{1,2}(?::[0-9]{1,2})?)?)["']?`)
+	// Match dash and slash formats. Only the dates are changed so paths keep their slashes.
+	re := regexp.MustCompile(`date(\s*)([<>=!]+)\s*["']?([0-9]{4}[-/][0-9]{1,2}[-/][0-9]{1,2}(?:\s+[0-9]{1,2}:[0-9]{1,2}(?::[0-9]{1,2})?)?)["']?`)
 	matches := re.FindAllStringSubmatchIndex(filter, -1)
 	if len(matches) == 0 {
 		return filter, nil
@@ -55,7 +52,8 @@ func processFilter(filter string) (string, error) {
 		m := matches[i]
 		space := filter[m[2]:m[3]]
 		operator := filter[m[4]:m[5]]
-		dateStr := filter[m[6]:m[7]]
+		// Convert slashes to dashes for user convenience.
+		dateStr := strings.ReplaceAll(filter[m[6]:m[7]], "/", "-")
 		timestamp, err := parseHumanDate(dateStr)
 		if err != nil {
 			return "", fmt.Errorf("invalid date format in filter: %s", err)
@@ -66,21 +64,16 @@ func processFilter(filter string) (string, error) {
 	return result, nil
 }
 
-/* GetInput checks if the input is from a terminal and returns the input file.
+/* GetInput opens the body file, or returns stdin for "-" or when no file is given and stdin isn't a
+ * terminal. Returns nil when there is no input.
  */
-func GetInput() *os.File {
-	if !isatty.IsTerminal(os.Stdin.Fd()) {
+func GetInput(filename string) *os.File {
+	if filename == "-" || (filename == "" && !isatty.IsTerminal(os.Stdin.Fd())) {
 		return os.Stdin
 	}
 
-	if flag.NArg() == 0 {
-		flag.Usage()
-	}
-
-	filename := flag.Arg(0)
-
-	if filename == "-" {
-		return os.Stdin
+	if filename == "" {
+		return nil
 	}
 
 	f, err := os.Open(filename)
@@ -120,11 +113,9 @@ Use -strict flag to show only matching timestamps instead of all timestamps for
 	fmt.Fprint(os.Stderr, helpText)
 }
 
-/* ProcessBody processes the body file.
+/* selectFilter returns the filter to apply and the timestamp types it is restricted to.
  */
-func ProcessBody(f *os.File, flagProcess *bool, strict *bool, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
-
-	body := bodyfile.NewReader(f)
+func selectFilter(filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) (string, []string) {
 
 	// Determine which timestamp types to filter on.
 	var timestampTypes []string
@@ -166,83 +157,150 @@ func ProcessBody(f *os.File, flagProcess *bool, strict *bool, filter *string, mo
 		finalFilter = processedFilter
 	}
 
-	if finalFilter != "" {
-		err := body.AddFilter(finalFilter)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Could not add filter: %s", err)
-			os.Exit(2)
-		}
-	}
+	return finalFilter, timestampTypes
+}
 
-	body.Strict = *strict
+/* ProcessBody processes the body file.
+ */
+func ProcessBody(f io.Reader, strict *bool, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
+
+	body := bodyfile.NewReader(f)
 
-	count, err := body.Slurp()
+	finalFilter, timestampTypes := selectFilter(filter, modifiedFilter, accessFilter, ctimeFilter)
+
+	ef, err := newEntryFilter(finalFilter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not add filter: %s", err)
+		os.Exit(2)
+	}
+
+	timeline, err := ef.Slurp(body, *strict)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Could not read all the content: %s", err)
 		os.Exit(3)
 	}
 
 	// If no results found and a filter was used, show helpful message.
-	if count == 0 && (*filter != "" || *modifiedFilter != "" || *accessFilter != "" || *ctimeFilter != "") {
+	if len(timeline) == 0 && (*filter != "" || *modifiedFilter != "" || *accessFilter != "" || *ctimeFilter != "") {
 		showFilterHelp(*filter)
 		return
 	}
 
 	// Iterate through the body file.
-	for {
-		tsEntry, err := body.Next()
+	for i := range timeline {
+		printEntry(&timeline[i], timestampTypes)
+	}
+}
+
+/* collectTimeline returns the sorted timeline entries matching the filter from the body file,
+ * or from the index database when dbPath is set.
+ */
+func collectTimeline(f io.Reader, dbPath string, strict bool, finalFilter string, timestampTypes []string) ([]bodyfile.TimeStampedEntry, error) {
+
+	var timeline []bodyfile.TimeStampedEntry
 
-		if err == io.EOF {
-			break
+	if dbPath != "" {
+
+		all, err := indexTimeline(dbPath, finalFilter, strict)
+		if err != nil {
+			return nil, err
 		}
+		timeline = all
 
+	} else {
+
+		ef, err := newEntryFilter(finalFilter)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error while iterating: %s", err)
-			os.Exit(4)
+			return nil, fmt.Errorf("could not add filter: %s", err)
 		}
 
-		// Apply timestamp-specific filtering if needed
-		if len(timestampTypes) > 0 {
-			shouldShow := false
-			for _, tsType := range timestampTypes {
-				switch tsType {
-				case "modified":
-					if tsEntry.Time.Equal(tsEntry.Entry.ModificationTime) {
-						shouldShow = true
-					}
-				case "access":
-					if tsEntry.Time.Equal(tsEntry.Entry.AccessTime) {
-						shouldShow = true
-					}
-				case "ctime":
-					if tsEntry.Time.Equal(tsEntry.Entry.ChangeTime) {
-						shouldShow = true
-					}
+		if timeline, err = ef.Slurp(bodyfile.NewReader(f), strict); err != nil {
+			return nil, err
+		}
+	}
+
+	// Drop the timestamps excluded by -modified, -access or -ctime.
+	shown := timeline[:0]
+	for i := range timeline {
+		if showTimestamp(&timeline[i], timestampTypes) {
+			shown = append(shown, timeline[i])
+		}
+	}
+
+	return shown, nil
+}
+
+/* showTimestamp checks if the timeline entry is one of the timestamp types being filtered on.
+ */
+func showTimestamp(tsEntry *bodyfile.TimeStampedEntry, timestampTypes []string) bool {
+
+	// Apply timestamp-specific filtering if needed
+	if len(timestampTypes) > 0 {
+		shouldShow := false
+		for _, tsType := range timestampTypes {
+			switch tsType {
+			case "modified":
+				if tsEntry.Time.Equal(tsEntry.Entry.ModificationTime) {
+					shouldShow = true
+				}
+			case "access":
+				if tsEntry.Time.Equal(tsEntry.Entry.AccessTime) {
+					shouldShow = true
+				}
+			case "ctime":
+				if tsEntry.Time.Equal(tsEntry.Entry.ChangeTime) {
+					shouldShow = true
 				}
-			}
-			if !shouldShow {
-				continue
 			}
 		}
+		return shouldShow
+	}
 
-		// Get the entry type.
-		mChar := entryType(tsEntry, tsEntry.Entry.ModificationTime, "m")
-		aChar := entryType(tsEntry, tsEntry.Entry.AccessTime, "a")
-		cChar := entryType(tsEntry, tsEntry.Entry.ChangeTime, "c")
-		bChar := entryType(tsEntry, tsEntry.Entry.CreationTime, "b")
+	return true
+}
+
+/* printEntry prints a timeline line for the entry unless it is excluded by the timestamp types.
+ */
+func printEntry(tsEntry *bodyfile.TimeStampedEntry, timestampTypes []string) {
 
-		// Get the MACB line.
-		macbLine := fmt.Sprintf("%s%s%s%s", mChar, aChar, cChar, bChar)
+	if !showTimestamp(tsEntry, timestampTypes) {
+		return
+	}
 
-		// Get the date, hour, minute, and second.
-		date := tsEntry.Time.Format("2006-01-02")
-		hour := fmt.Sprintf("%02d", tsEntry.Time.Hour())
-		min := fmt.Sprintf(":%02d", tsEntry.Time.Minute())
-		sec := fmt.Sprintf(":%02d:", tsEntry.Time.Second())
+	// Print the entry.
+	fmt.Println(formatEntry(tsEntry))
+}
 
-		// Print the entry.
-		fmt.Printf("%s %s%s%s %s %s\n", date, hour, min, sec, macbLine, tsEntry.Entry.Name)
+/* formatEntry returns the timeline line for the entry.
+ */
+func formatEntry(tsEntry *bodyfile.TimeStampedEntry) string {
+
+	// Get the entry type.
+	mChar := entryType(tsEntry, tsEntry.Entry.ModificationTime, "m")
+	aChar := entryType(tsEntry, tsEntry.Entry.AccessTime, "a")
+	cChar := entryType(tsEntry, tsEntry.Entry.ChangeTime, "c")
+	bChar := entryType(tsEntry, tsEntry.Entry.CreationTime, "b")
+
+	// Get the MACB line.
+	macbLine := fmt.Sprintf("%s%s%s%s", mChar, aChar, cChar, bChar)
+
+	// Get the date, hour, minute, and second in the display timezone.
+	t := tsEntry.Time.In(displayLocation)
+	date := t.Format("2006-01-02")
+	hour := fmt.Sprintf("%02d", t.Hour())
+	min := fmt.Sprintf(":%02d", t.Minute())
+	sec := fmt.Sprintf(":%02d:", t.Second())
+
+	line := fmt.Sprintf("%s %s%s%s %s %s", date, hour, min, sec, macbLine, tsEntry.Entry.Name)
+
+	// Resolved owners, extended attributes loaded with -xattrs, type mismatches and hash sets are shown after the name.
+	for _, label := range []string{ownerLabel(tsEntry.Entry), xattrLabel(tsEntry.Entry), typeLabel(tsEntry.Entry), knownLabel(tsEntry.Entry)} {
+		if label != "" {
+			line += " " + label
+		}
 	}
+
+	return line
 }
 
 /* entryType returns the entry type.
@@ -2,8 +2,14 @@ package processBody
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
 )
 
 func TestParseHumanDate_Valid(t *testing.T) {
@@ -26,6 +32,29 @@ func TestParseHumanDate_Valid(t *testing.T) {
 	}
 }
 
+func TestFilterTimezone(t *testing.T) {
+	defer func() { displayLocation = time.UTC }()
+	displayLocation = time.FixedZone("UTC+2", 2*3600)
+
+	// 11:30 UTC is shown at 13:30.
+	at := time.Date(2025, 6, 19, 11, 30, 0, 0, time.UTC)
+
+	if ts, err := parseHumanDate("2025-06-19 13:30"); err != nil || ts != at.Unix() {
+		t.Errorf("parseHumanDate() = %d, %v, want %d", ts, err, at.Unix())
+	}
+
+	for _, filter := range []string{"hour == 13", "hour == 13 && min == 30"} {
+		ef, err := newEntryFilter(filter)
+		if err != nil {
+			t.Fatalf("newEntryFilter(%q) returned error: %v", filter, err)
+		}
+		matched, err := ef.Match(&bodyfile.Entry{Name: "/tmp/a", AccessTime: at, ModificationTime: at, ChangeTime: at, CreationTime: at})
+		if err != nil || !matched {
+			t.Errorf("%q = %v, %v, want a match", filter, matched, err)
+		}
+	}
+}
+
 func TestParseHumanDate_Invalid(t *testing.T) {
 	invalids := []string{
 		"2025/06/19", // Slashes are not handled by parseHumanDate
@@ -64,4 +93,315 @@ func TestProcessFilter(t *testing.T) {
 	if result != expected {
 		t.Errorf("processFilter for slashed date = %q, want %q", result, expected)
 	}
+
+	// Slashes outside of dates are kept for path filters.
+	pathFilter := "path =~ \"/etc/\" && date > \"2025/06/19 13:47:35\""
+	result, err = processFilter(pathFilter)
+	if want := "path =~ \"/etc/\" && " + expected; err != nil || result != want {
+		t.Errorf("processFilter(%q) = %q, %v, want %q", pathFilter, result, err, want)
+	}
+}
+
+func TestDateBounds(t *testing.T) {
+	tests := []struct {
+		filter   string
+		from, to int64
+		ok       bool
+	}{
+		{"date > 100 && date < 200", 101, 199, true},
+		{"date >= 100 && hour > 12", 100, math.MaxInt64, true},
+		{"date == 100", 100, 100, true},
+		{"date > 100 || date < 50", 0, 0, false},
+		{"!(date > 100)", 0, 0, false},
+		{"hour > 12", 0, 0, false},
+	}
+	for _, test := range tests {
+		from, to, ok := dateBounds(test.filter)
+		if ok != test.ok {
+			t.Errorf("dateBounds(%q) ok = %v, want %v", test.filter, ok, test.ok)
+			continue
+		}
+		if ok && (from != test.from || to != test.to) {
+			t.Errorf("dateBounds(%q) = %d, %d, want %d, %d", test.filter, from, to, test.from, test.to)
+		}
+	}
+}
+
+func TestMD5Bound(t *testing.T) {
+	tests := []struct {
+		filter string
+		md5    string
+		ok     bool
+	}{
+		{`md5 == "d41d8cd98f00b204e9800998ecf8427e" && date > 100`, "d41d8cd98f00b204e9800998ecf8427e", true},
+		{`md5=='0CC175B9C0F1B6A831C399E269772661'`, "0CC175B9C0F1B6A831C399E269772661", true},
+		{`md5 == "d41d8cd98f00b204e9800998ecf8427e" || path == "/tmp/a"`, "", false},
+		{`md5 != "d41d8cd98f00b204e9800998ecf8427e"`, "", false},
+		{`md5 == "d41d8"`, "", false},
+	}
+	for _, test := range tests {
+		md5, ok := md5Bound(test.filter)
+		if ok != test.ok || md5 != test.md5 {
+			t.Errorf("md5Bound(%q) = %q, %v, want %q, %v", test.filter, md5, ok, test.md5, test.ok)
+		}
+	}
+}
+
+func TestPathBound(t *testing.T) {
+	tests := []struct {
+		filter string
+		path   string
+		ok     bool
+	}{
+		{`path == "/etc/passwd" && date > 100`, "/etc/passwd", true},
+		{`p=='/tmp/a b'`, "/tmp/a b", true},
+		{`path == "/etc/passwd" || date > 100`, "", false},
+		{`path != "/etc/passwd"`, "", false},
+		{`path =~ "^/etc"`, "", false},
+		{`path == "C:\\Windows"`, "", false},
+	}
+	for _, test := range tests {
+		path, ok := pathBound(test.filter)
+		if ok != test.ok || path != test.path {
+			t.Errorf("pathBound(%q) = %q, %v, want %q, %v", test.filter, path, ok, test.path, test.ok)
+		}
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	tests := []struct {
+		offset   time.Duration
+		expected string
+	}{
+		{0, "T+00:00:00"},
+		{72 * time.Second, "T+00:01:12"},
+		{-(2*time.Hour + 5*time.Second), "T-02:00:05"},
+	}
+	for _, test := range tests {
+		if result := formatOffset(test.offset); result != test.expected {
+			t.Errorf("formatOffset(%s) = %q, want %q", test.offset, result, test.expected)
+		}
+	}
+}
+
+func TestColumnFilter(t *testing.T) {
+	defer func() { bodyColumns, columnValues = nil, nil }()
+
+	sample := "1536:JXnkKfAsLHm7Ks/gKIIVhfWV7iEJaKu+inGEFaQVPkKgyLN8/O1:JXk8dm7KDmZm9uZ2c8G"
+	variant := "1536:JDkKfAsLHm7Ks/gKIIVhfWV7iEJaKu+inGEFaQVPkKgyLN8/O1:Jo8dm7KDmZm9uZ2c8G"
+
+	bodyColumns = []string{"type", "entropy", "ssdeep"}
+	columnValues = map[string]map[string]string{
+		"/tmp/invoice.pdf": {"type": "elf", "entropy": "5.94", "ssdeep": variant},
+		"/tmp/notes.txt":   {"type": "text", "entropy": "2.25", "ssdeep": ""},
+	}
+
+	tests := []struct {
+		filter string
+		path   string
+		want   bool
+	}{
+		{"ext_mismatch", "/tmp/invoice.pdf", true},
+		{"ext_mismatch", "/tmp/notes.txt", false},
+		{`type == "elf" && entropy > 5.5`, "/tmp/invoice.pdf", true},
+		{"entropy < 0", "/tmp/unknown", true},
+		{fmt.Sprintf("similar_to(%q, 80)", sample), "/tmp/invoice.pdf", true},
+		{fmt.Sprintf("similar_to(%q, 100)", sample), "/tmp/invoice.pdf", false},
+		{fmt.Sprintf("similar_to(%q, 80)", sample), "/tmp/notes.txt", false},
+	}
+	for _, test := range tests {
+		ef, err := newEntryFilter(test.filter)
+		if err != nil {
+			t.Fatalf("newEntryFilter(%q) returned error: %v", test.filter, err)
+		}
+		matched, err := ef.Match(&bodyfile.Entry{Name: test.path})
+		if err != nil || matched != test.want {
+			t.Errorf("%q on %s = %v, %v, want %v", test.filter, test.path, matched, err, test.want)
+		}
+	}
+}
+
+func TestKnownFilter(t *testing.T) {
+	defer func() { goodSets, badSets, hideKnownGood = nil, nil, false }()
+
+	good, _ := common.ReadHashSet("NSRLFile.txt", strings.NewReader("d41d8cd98f00b204e9800998ecf8427e\n0cc175b9c0f1b6a831c399e269772661\n"))
+	bad, _ := common.ReadHashSet("iocs.md5", strings.NewReader("0cc175b9c0f1b6a831c399e269772661\n"))
+	goodSets, badSets = []*common.HashSet{good}, []*common.HashSet{bad}
+
+	tests := []struct {
+		filter string
+		md5    string
+		hide   bool
+		want   bool
+	}{
+		{`known == "good" && hashset == "NSRLFile.txt"`, "d41d8cd98f00b204e9800998ecf8427e", false, true},
+		{`known == "bad"`, "0cc175b9c0f1b6a831c399e269772661", false, true},
+		{`known == "unknown"`, "92eb5ffee6ae2fec3ad71c777531578f", false, true},
+		{`known == "unknown"`, "0", false, true},
+		{"", "d41d8cd98f00b204e9800998ecf8427e", true, false},
+		{"", "0cc175b9c0f1b6a831c399e269772661", true, true},
+	}
+	for _, test := range tests {
+		hideKnownGood = test.hide
+		ef, err := newEntryFilter(test.filter)
+		if err != nil {
+			t.Fatalf("newEntryFilter(%q) returned error: %v", test.filter, err)
+		}
+		matched, err := ef.Match(&bodyfile.Entry{Name: "/tmp/a", MD5: test.md5})
+		if err != nil || matched != test.want {
+			t.Errorf("%q on %s (hide %v) = %v, %v, want %v", test.filter, test.md5, test.hide, matched, err, test.want)
+		}
+	}
+}
+
+/*
+Returns the timeline of entries with the given modification, access, change and creation times.
+*/
+func summaryTimeline(times ...[4]time.Time) []bodyfile.TimeStampedEntry {
+
+	var timeline []bodyfile.TimeStampedEntry
+	for _, ts := range times {
+		e := &bodyfile.Entry{ModificationTime: ts[0], AccessTime: ts[1], ChangeTime: ts[2], CreationTime: ts[3]}
+		timeline = append(timeline, timestampedEntries(e, false)...)
+	}
+
+	return timeline
+}
+
+func TestBucketEvents(t *testing.T) {
+	at := func(day, hour, min, sec int) time.Time { return time.Date(2025, 6, day, hour, min, sec, 0, time.UTC) }
+
+	// A file with four distinct times, and one with all its times the same.
+	same := at(16, 10, 5, 30)
+	timeline := summaryTimeline(
+		[4]time.Time{at(16, 10, 5, 10), at(16, 10, 5, 50), at(16, 10, 40, 0), at(17, 9, 0, 0)},
+		[4]time.Time{same, same, same, same},
+	)
+
+	tests := []struct {
+		bucket string
+		want   []string
+	}{
+		{"minute", []string{"2025-06-16 10:05 m2 a2 c1 b1", "2025-06-16 10:40 m0 a0 c1 b0", "2025-06-17 09:00 m0 a0 c0 b1"}},
+		{"hour", []string{"2025-06-16 10:00 m2 a2 c2 b1", "2025-06-17 09:00 m0 a0 c0 b1"}},
+		{"day", []string{"2025-06-16 m2 a2 c2 b1", "2025-06-17 m0 a0 c0 b1"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, b := range bucketEvents(timeline, test.bucket) {
+			got = append(got, fmt.Sprintf("%s m%d a%d c%d b%d", b.start.Format(bucketFormats[test.bucket]), b.counts["m"], b.counts["a"], b.counts["c"], b.counts["b"]))
+		}
+		if strings.Join(got, "; ") != strings.Join(test.want, "; ") {
+			t.Errorf("bucketEvents(%s) = %q, want %q", test.bucket, got, test.want)
+		}
+	}
+}
+
+func TestHeatmapCounts(t *testing.T) {
+	defer func() { displayLocation = time.UTC }()
+
+	monday := time.Date(2025, 6, 16, 10, 5, 0, 0, time.UTC)
+	tuesday := time.Date(2025, 6, 17, 9, 0, 0, 0, time.UTC)
+	timeline := summaryTimeline(
+		[4]time.Time{monday, monday.Add(time.Minute), monday.Add(30 * time.Minute), tuesday},
+		[4]time.Time{monday, monday, monday, monday},
+	)
+
+	tests := []struct {
+		location *time.Location
+		weekday  time.Weekday
+		hour     int
+		count    int
+	}{
+		{time.UTC, time.Monday, 10, 4},
+		{time.UTC, time.Tuesday, 9, 1},
+		{time.UTC, time.Monday, 9, 0},
+		// The cells follow the display timezone.
+		{time.FixedZone("UTC-10", -10*3600), time.Monday, 0, 4},
+		{time.FixedZone("UTC-10", -10*3600), time.Monday, 23, 1},
+	}
+
+	for _, test := range tests {
+		displayLocation = test.location
+		heatmap, most := heatmapCounts(timeline)
+		if heatmap[test.weekday][test.hour] != test.count || most != 4 {
+			t.Errorf("heatmapCounts() in %s [%s][%d] = %d, most %d, want %d, most 4", test.location, test.weekday, test.hour, heatmap[test.weekday][test.hour], most, test.count)
+		}
+	}
+}
+
+// Entries of the -view files tests, with the times of 2025-06-10 and 2025-06-19 for /a and /b.
+const filesBody = "0|/a|1|33188|0|0|30|1749513600|1750291200|1749513600|1749513600\n" +
+	"0|/b|2|16877|1000|100|10|1750291200|1749513600|1749513600|1749513600\n" +
+	"0|/c|3|420|5|5|20|1749600000|1749600000|1750000000|1750100000\n"
+
+func TestSortEntries(t *testing.T) {
+	tests := []struct {
+		column  string
+		reverse bool
+		want    string
+	}{
+		{"name", false, "/a /b /c"},
+		{"name", true, "/c /b /a"},
+		{"mtime", false, "/b /c /a"},
+		{"atime", false, "/a /c /b"},
+		{"ctime", false, "/a /b /c"},
+		{"ctime", true, "/c /a /b"},
+		{"crtime", true, "/c /a /b"},
+		{"size", false, "/b /c /a"},
+		{"mode", false, "/c /b /a"},
+		{"uid", false, "/a /c /b"},
+		{"gid", true, "/b /c /a"},
+		{"inode", true, "/c /b /a"},
+	}
+
+	for _, test := range tests {
+		entries, err := matchingEntries(strings.NewReader(filesBody), "", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sortEntries(entries, fileColumns[test.column], test.reverse)
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if got := strings.Join(names, " "); got != test.want {
+			t.Errorf("-sort %s -reverse=%v = %s, want %s", test.column, test.reverse, got, test.want)
+		}
+	}
+}
+
+func TestMatchingEntriesTimestampTypes(t *testing.T) {
+	after := `date > "2025-06-15"`
+	none := ""
+
+	tests := []struct {
+		modified, access, ctime, filter *string
+		want                            string
+	}{
+		{&after, &none, &none, &none, "/a"},
+		{&none, &after, &none, &none, "/b"},
+		{&none, &none, &after, &none, "/c"},
+		{&none, &none, &none, &after, "/a /b /c"},
+	}
+
+	for _, test := range tests {
+		finalFilter, timestampTypes := selectFilter(test.filter, test.modified, test.access, test.ctime)
+
+		entries, err := matchingEntries(strings.NewReader(filesBody), "", finalFilter, timestampTypes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if got := strings.Join(names, " "); got != test.want {
+			t.Errorf("matchingEntries(%q, %v) = %s, want %s", finalFilter, timestampTypes, got, test.want)
+		}
+	}
 }
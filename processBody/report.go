@@ -0,0 +1,258 @@
+package processBody
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
+)
+
+/*
+ReportOptions are the settings of the reports.
+*/
+type ReportOptions struct {
+
+	// Lowest entropy of the files in the entropy report.
+	MinEntropy float64
+
+	// Largest gap between the modification times of files clustered together.
+	Gap time.Duration
+
+	// Lowest ssdeep score, from 0 to 100, of files grouped together by the similar report.
+	Similarity int
+}
+
+/*
+report is a -report over the matching entries, built from an extended column of the body files.
+*/
+type report struct {
+	column  string
+	collect string
+	run     func(entries []reportEntry, opts ReportOptions)
+}
+
+// Reports by name.
+var reports = map[string]report{
+	"mismatches": {"type", "collect -types", reportMismatches},
+	"entropy":    {"entropy", "collect -entropy", reportEntropy},
+	"similar":    {"ssdeep", "collect -ssdeep", reportSimilar},
+}
+
+/*
+reportEntry is an entry matching the filter with the body file it came from and its extended
+columns, since a report can read several body files.
+*/
+type reportEntry struct {
+	*bodyfile.Entry
+	source  string
+	columns map[string]string
+}
+
+// Number of body files the report reads. Names are shown with their body file when there are several.
+var reportSources int
+
+/*
+Returns the name of the entry as shown in a report.
+*/
+func (e reportEntry) label() string {
+
+	if reportSources > 1 {
+		return e.Name + " (" + e.source + ")"
+	}
+
+	return e.Name
+}
+
+/*
+ProcessReport prints a report on the entries of the body files matching the filter. Reports use the
+extended columns, which only body files have.
+*/
+func ProcessReport(sources []string, dbPath string, name string, tz string, identityFile string, opts ReportOptions, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
+
+	r, ok := reports[name]
+	if !ok {
+		var names []string
+		for n := range reports {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "Unknown report: %s (use one of %s)\n", name, strings.Join(names, ", "))
+		os.Exit(2)
+	}
+
+	if dbPath != "" {
+		fmt.Fprintln(os.Stderr, "Reports need a body file, the index database doesn't keep the extended columns.")
+		os.Exit(2)
+	}
+
+	// Without a body file, stdin is read when it isn't a terminal.
+	if len(sources) == 0 {
+		sources = []string{""}
+	}
+	reportSources = len(sources)
+
+	var entries []reportEntry
+
+	for _, source := range sources {
+
+		input := GetInput(source)
+		if input == nil {
+			fmt.Fprintln(os.Stderr, "No body file to report on.")
+			os.Exit(2)
+		}
+
+		f := ReadInput(input, tz, identityFile)
+		if !hasColumn(r.column) {
+			fmt.Fprintf(os.Stderr, "%s has no %s column, collect it with %s.\n", sourceName(source), r.column, r.collect)
+			os.Exit(1)
+		}
+
+		// The dates of the filter are read in the timezone of each body file.
+		finalFilter, timestampTypes := selectFilter(filter, modifiedFilter, accessFilter, ctimeFilter)
+
+		matched, err := matchingEntries(f, "", finalFilter, timestampTypes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read all the content of %s: %s", sourceName(source), err)
+			os.Exit(3)
+		}
+
+		for _, e := range matched {
+			entries = append(entries, reportEntry{e, sourceName(source), columnValues[e.Name]})
+		}
+
+		if input != os.Stdin {
+			input.Close()
+		}
+	}
+
+	r.run(entries, opts)
+}
+
+/*
+Returns the name of a body file given on the command line.
+*/
+func sourceName(source string) string {
+
+	if source == "" || source == "-" {
+		return "stdin"
+	}
+
+	return source
+}
+
+/*
+Lists the files whose extension contradicts the type detected from their content.
+*/
+func reportMismatches(entries []reportEntry, opts ReportOptions) {
+
+	var mismatches []reportEntry
+	for _, e := range entries {
+		if common.ExtensionMismatch(e.Name, e.columns["type"]) {
+			mismatches = append(mismatches, e)
+		}
+	}
+
+	if len(mismatches) == 0 {
+		fmt.Println("No file has an extension contradicting its content.")
+		return
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].label() < mismatches[j].label() })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MTIME\tSIZE\tEXTENSION\tTYPE\tNAME")
+
+	for _, e := range mismatches {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", e.ModificationTime.In(displayLocation).Format("2006-01-02 15:04:05"),
+			e.Size, strings.ToLower(path.Ext(e.Name)), e.columns["type"], e.label())
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d of %d entries have an extension contradicting their content\n", len(mismatches), len(entries))
+}
+
+/*
+Lists the files with an entropy of at least MinEntropy, clustered by directory and by modification
+times less than Gap apart. Encrypted files left by ransomware show up as big clusters of files
+modified within minutes, the biggest are listed first.
+*/
+func reportEntropy(entries []reportEntry, opts ReportOptions) {
+
+	byDir := make(map[string][]reportEntry)
+	high := 0
+
+	for _, e := range entries {
+		if parseEntropy(e.columns["entropy"]) >= opts.MinEntropy {
+			dir := path.Dir(e.Name)
+			if reportSources > 1 {
+				dir += " (" + e.source + ")"
+			}
+			byDir[dir] = append(byDir[dir], e)
+			high++
+		}
+	}
+
+	if high == 0 {
+		fmt.Printf("No file has an entropy of %.2f or more.\n", opts.MinEntropy)
+		return
+	}
+
+	type cluster struct {
+		dir     string
+		entries []reportEntry
+	}
+
+	var clusters []cluster
+
+	for dir, files := range byDir {
+
+		sort.Slice(files, func(i, j int) bool { return files[i].ModificationTime.Before(files[j].ModificationTime) })
+
+		start := 0
+		for i := 1; i <= len(files); i++ {
+			if i == len(files) || files[i].ModificationTime.Sub(files[i-1].ModificationTime) > opts.Gap {
+				clusters = append(clusters, cluster{dir, files[start:i]})
+				start = i
+			}
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		a, b := clusters[i], clusters[j]
+		if len(a.entries) != len(b.entries) {
+			return len(a.entries) > len(b.entries)
+		}
+		return a.entries[0].ModificationTime.Before(b.entries[0].ModificationTime)
+	})
+
+	format := "2006-01-02 15:04:05"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for _, c := range clusters {
+
+		first, last := c.entries[0], c.entries[len(c.entries)-1]
+		total := 0.0
+		for _, e := range c.entries {
+			total += parseEntropy(e.columns["entropy"])
+		}
+
+		fmt.Fprintf(w, "%s: %d files modified %s to %s, mean entropy %.2f\n", c.dir, len(c.entries),
+			first.ModificationTime.In(displayLocation).Format(format), last.ModificationTime.In(displayLocation).Format(format), total/float64(len(c.entries)))
+
+		for _, e := range c.entries {
+			fmt.Fprintf(w, "  %s\t%s\t%d\t%s\t%s\n", e.ModificationTime.In(displayLocation).Format(format), e.columns["entropy"], e.Size, e.columns["type"], e.Name)
+		}
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d files with an entropy of %.2f or more in %d clusters\n", high, opts.MinEntropy, len(clusters))
+}
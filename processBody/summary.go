@@ -0,0 +1,332 @@
+package processBody
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/airbus-cert/bodyfile"
+)
+
+// MACB types in the order they are printed.
+var macbTypes = []string{"m", "a", "c", "b"}
+
+// Names of the MACB types used for the histogram titles.
+var macbNames = map[string]string{
+	"m": "Modified",
+	"a": "Accessed",
+	"c": "Changed",
+	"b": "Born",
+}
+
+// Time formats used for each bucket size.
+var bucketFormats = map[string]string{
+	"minute": "2006-01-02 15:04",
+	"hour":   "2006-01-02 15:00",
+	"day":    "2006-01-02",
+}
+
+// Characters used for the heatmap from no activity to the most activity.
+const heatmapShades = " .:-=+*#%@"
+
+// Width of the longest histogram bar.
+const barWidth = 50
+
+/*
+bucketCounts holds the number of events of each MACB type for one bucket.
+*/
+type bucketCounts struct {
+	start  time.Time
+	counts map[string]int
+}
+
+/*
+total returns the number of events in the bucket.
+*/
+func (b *bucketCounts) total() int {
+
+	total := 0
+	for _, count := range b.counts {
+		total += count
+	}
+
+	return total
+}
+
+/*
+bucketEvents groups the timeline entries into buckets of the given size and counts each MACB type.
+*/
+func bucketEvents(timeline []bodyfile.TimeStampedEntry, bucket string) []*bucketCounts {
+
+	buckets := make(map[time.Time]*bucketCounts)
+
+	for i := range timeline {
+
+		tsEntry := &timeline[i]
+		t := tsEntry.Time.In(displayLocation)
+
+		var start time.Time
+		switch bucket {
+		case "minute":
+			start = t.Truncate(time.Minute)
+		case "hour":
+			start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		default:
+			start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+
+		b, ok := buckets[start]
+		if !ok {
+			b = &bucketCounts{start: start, counts: make(map[string]int)}
+			buckets[start] = b
+		}
+
+		for _, c := range macbTypes {
+			if entryType(tsEntry, macbTime(tsEntry.Entry, c), c) == c {
+				b.counts[c]++
+			}
+		}
+	}
+
+	sorted := make([]*bucketCounts, 0, len(buckets))
+	for _, b := range buckets {
+		sorted = append(sorted, b)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start.Before(sorted[j].start)
+	})
+
+	return sorted
+}
+
+/*
+macbTime returns the entry's timestamp for a MACB type.
+*/
+func macbTime(e *bodyfile.Entry, c string) time.Time {
+
+	switch c {
+	case "m":
+		return e.ModificationTime
+	case "a":
+		return e.AccessTime
+	case "c":
+		return e.ChangeTime
+	}
+
+	return e.CreationTime
+}
+
+/*
+printHistograms prints an ASCII histogram of the buckets for each MACB type.
+*/
+func printHistograms(buckets []*bucketCounts, bucket string) {
+
+	for _, c := range macbTypes {
+
+		most := 0
+		for _, b := range buckets {
+			most = max(most, b.counts[c])
+		}
+
+		fmt.Printf("\n%s (%s) per %s\n", macbNames[c], c, bucket)
+
+		if most == 0 {
+			fmt.Println("  no events")
+			continue
+		}
+
+		// Only buckets with activity are printed.
+		for _, b := range buckets {
+			count := b.counts[c]
+			if count == 0 {
+				continue
+			}
+			bar := strings.Repeat("#", max(1, count*barWidth/most))
+			fmt.Printf("  %-16s %-*s %d\n", b.start.Format(bucketFormats[bucket]), barWidth, bar, count)
+		}
+	}
+}
+
+/*
+heatmapCounts returns the number of events per weekday and hour of the day, and the highest one.
+*/
+func heatmapCounts(timeline []bodyfile.TimeStampedEntry) ([7][24]int, int) {
+
+	var heatmap [7][24]int
+	most := 0
+
+	for i := range timeline {
+		t := timeline[i].Time.In(displayLocation)
+		heatmap[t.Weekday()][t.Hour()]++
+		most = max(most, heatmap[t.Weekday()][t.Hour()])
+	}
+
+	return heatmap, most
+}
+
+/*
+printHeatmap prints the number of events per hour of the day for each weekday.
+*/
+func printHeatmap(timeline []bodyfile.TimeStampedEntry) {
+
+	heatmap, most := heatmapCounts(timeline)
+
+	fmt.Printf("\nHour of day by weekday (%q = least to most activity)\n", heatmapShades)
+	fmt.Print("           ")
+	for hour := 0; hour < 24; hour++ {
+		fmt.Printf("%02d ", hour)
+	}
+	fmt.Println()
+
+	// Start the week on Monday.
+	for i := 1; i <= 7; i++ {
+
+		day := time.Weekday(i % 7)
+		fmt.Printf("  %-9s", day)
+
+		for hour := 0; hour < 24; hour++ {
+			shade := ' '
+			if count := heatmap[day][hour]; count > 0 {
+				shade = rune(heatmapShades[1+(count*(len(heatmapShades)-2))/most])
+			}
+			fmt.Printf("%c%c ", shade, shade)
+		}
+		fmt.Println()
+	}
+}
+
+/*
+writeBucketsCSV writes the buckets as CSV.
+*/
+func writeBucketsCSV(f *os.File, buckets []*bucketCounts, bucket string) error {
+
+	if _, err := fmt.Fprintln(f, "bucket,m,a,c,b,total"); err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		_, err := fmt.Fprintf(f, "%s,%d,%d,%d,%d,%d\n", b.start.Format(bucketFormats[bucket]), b.counts["m"], b.counts["a"], b.counts["c"], b.counts["b"], b.total())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+writeBucketsSVG writes the buckets as a standalone SVG chart with one stacked bar per bucket.
+*/
+func writeBucketsSVG(f *os.File, buckets []*bucketCounts, bucket string) error {
+
+	colors := map[string]string{"m": "#d62728", "a": "#1f77b4", "c": "#ff7f0e", "b": "#2ca02c"}
+
+	const height = 300
+	const margin = 40
+	barSpace := 12
+	// Leave room for the legend when there are only a few buckets.
+	width := max(400, margin*2+len(buckets)*barSpace)
+
+	most := 1
+	for _, b := range buckets {
+		most = max(most, b.total())
+	}
+
+	var svg strings.Builder
+
+	fmt.Fprintf(&svg, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"monospace\" font-size=\"10\">\n", width, height+margin*2)
+	fmt.Fprintf(&svg, "<text x=\"%d\" y=\"20\">Events per %s (max %d)</text>\n", margin, bucket, most)
+
+	for i, b := range buckets {
+
+		x := margin + i*barSpace
+		y := margin + height
+
+		for _, c := range macbTypes {
+			h := b.counts[c] * height / most
+			if h == 0 {
+				continue
+			}
+			y -= h
+			fmt.Fprintf(&svg, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"><title>%s %s: %d</title></rect>\n", x, y, barSpace-2, h, colors[c], b.start.Format(bucketFormats[bucket]), macbNames[c], b.counts[c])
+		}
+	}
+
+	// Label the first and last bucket.
+	if len(buckets) > 0 {
+		fmt.Fprintf(&svg, "<text x=\"%d\" y=\"%d\">%s</text>\n", margin, height+margin+15, buckets[0].start.Format(bucketFormats[bucket]))
+		fmt.Fprintf(&svg, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", width-margin, height+margin+30, buckets[len(buckets)-1].start.Format(bucketFormats[bucket]))
+	}
+
+	// Legend.
+	for i, c := range macbTypes {
+		fmt.Fprintf(&svg, "<rect x=\"%d\" y=\"26\" width=\"8\" height=\"8\" fill=\"%s\"/><text x=\"%d\" y=\"34\">%s</text>\n", margin+i*80, colors[c], margin+i*80+12, macbNames[c])
+	}
+
+	svg.WriteString("</svg>\n")
+
+	_, err := f.WriteString(svg.String())
+	return err
+}
+
+/* ProcessSummary prints histograms and a heatmap of the filtered events instead of the timeline.
+ */
+func ProcessSummary(f io.Reader, dbPath string, bucket string, summaryOut string, strict *bool, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
+
+	if _, ok := bucketFormats[bucket]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown bucket size: %s (use minute, hour or day)\n", bucket)
+		os.Exit(2)
+	}
+
+	finalFilter, timestampTypes := selectFilter(filter, modifiedFilter, accessFilter, ctimeFilter)
+
+	timeline, err := collectTimeline(f, dbPath, *strict, finalFilter, timestampTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read all the content: %s", err)
+		os.Exit(3)
+	}
+
+	// If no results found and a filter was used, show helpful message.
+	if len(timeline) == 0 && finalFilter != "" {
+		showFilterHelp(*filter)
+		return
+	}
+
+	buckets := bucketEvents(timeline, bucket)
+
+	fmt.Printf("%d events in %d %s buckets", len(timeline), len(buckets), bucket)
+	if len(timeline) > 0 {
+		fmt.Printf(" from %s to %s", timeline[0].Time.In(displayLocation).Format("2006-01-02 15:04:05"), timeline[len(timeline)-1].Time.In(displayLocation).Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println()
+
+	printHistograms(buckets, bucket)
+	printHeatmap(timeline)
+
+	if summaryOut == "" {
+		return
+	}
+
+	// Export the buckets based on the file's extension.
+	out, err := os.Create(summaryOut)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not create %s: %s", summaryOut, err)
+		os.Exit(5)
+	}
+	defer out.Close()
+
+	switch strings.ToLower(filepath.Ext(summaryOut)) {
+	case ".svg":
+		err = writeBucketsSVG(out, buckets, bucket)
+	default:
+		err = writeBucketsCSV(out, buckets, bucket)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write %s: %s", summaryOut, err)
+		os.Exit(5)
+	}
+}
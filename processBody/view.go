@@ -0,0 +1,177 @@
+package processBody
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/indexBody"
+)
+
+// Columns that -view files can be sorted by.
+var fileColumns = map[string]func(a, b *bodyfile.Entry) bool{
+	"name":   func(a, b *bodyfile.Entry) bool { return a.Name < b.Name },
+	"mtime":  func(a, b *bodyfile.Entry) bool { return a.ModificationTime.Before(b.ModificationTime) },
+	"atime":  func(a, b *bodyfile.Entry) bool { return a.AccessTime.Before(b.AccessTime) },
+	"ctime":  func(a, b *bodyfile.Entry) bool { return a.ChangeTime.Before(b.ChangeTime) },
+	"crtime": func(a, b *bodyfile.Entry) bool { return a.CreationTime.Before(b.CreationTime) },
+	"size":   func(a, b *bodyfile.Entry) bool { return a.Size < b.Size },
+	"mode":   lessMode,
+	"uid":    func(a, b *bodyfile.Entry) bool { return a.UID < b.UID },
+	"gid":    func(a, b *bodyfile.Entry) bool { return a.GID < b.GID },
+	"inode":  func(a, b *bodyfile.Entry) bool { return a.Inode < b.Inode },
+}
+
+/*
+lessMode compares the modes as numbers when possible since they are recorded as decimal or octal strings.
+*/
+func lessMode(a, b *bodyfile.Entry) bool {
+
+	x, errA := strconv.ParseUint(a.Mode, 0, 64)
+	y, errB := strconv.ParseUint(b.Mode, 0, 64)
+	if errA != nil || errB != nil {
+		return a.Mode < b.Mode
+	}
+
+	return x < y
+}
+
+/*
+matchingEntries returns the entries matching the filter from the body file, or from the
+index database when dbPath is set. When timestampTypes is set, the filter must have
+matched one of those timestamps.
+*/
+func matchingEntries(f io.Reader, dbPath string, finalFilter string, timestampTypes []string) ([]*bodyfile.Entry, error) {
+
+	ef, err := newEntryFilter(finalFilter)
+	if err != nil {
+		return nil, fmt.Errorf("could not add filter: %s", err)
+	}
+
+	var entries []*bodyfile.Entry
+
+	keep := func(e *bodyfile.Entry) {
+		if len(timestampTypes) == 0 || e.MatchingTimestamp&timestampBits(timestampTypes) != 0 {
+			entries = append(entries, e)
+		}
+	}
+
+	// Read the entries from the index.
+	if dbPath != "" {
+
+		ix, err := indexBody.Open(dbPath, false)
+		if err != nil {
+			return nil, err
+		}
+		defer ix.Close()
+
+		err = ix.ForEach(func(e *bodyfile.Entry) error {
+			matched, err := ef.Match(e)
+			if matched {
+				keep(e)
+			}
+			return err
+		})
+
+		return entries, err
+	}
+
+	// Read the entries from the body file, Read only returns matching entries.
+	body := bodyfile.NewReader(f)
+	for {
+		e, err := ef.Read(body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		keep(e)
+	}
+
+	return entries, nil
+}
+
+/*
+timestampBits converts the timestamp types used by -modified, -access and -ctime to bodyfile's timestamp bits.
+*/
+func timestampBits(timestampTypes []string) int {
+
+	bits := 0
+
+	for _, tsType := range timestampTypes {
+		switch tsType {
+		case "modified":
+			bits |= bodyfile.ModificationTime
+		case "access":
+			bits |= bodyfile.AccessTime
+		case "ctime":
+			bits |= bodyfile.ChangeTime
+		}
+	}
+
+	return bits
+}
+
+/* sortEntries sorts the entries by a column of fileColumns, keeping the order of equal entries.
+ */
+func sortEntries(entries []*bodyfile.Entry, less func(a, b *bodyfile.Entry) bool, reverse bool) {
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+/* ProcessFiles lists one row per entry with all four timestamps side by side.
+ */
+func ProcessFiles(f io.Reader, dbPath string, sortBy string, reverse bool, filter *string, modifiedFilter *string, accessFilter *string, ctimeFilter *string) {
+
+	less, ok := fileColumns[sortBy]
+	if !ok {
+		var columns []string
+		for column := range fileColumns {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		fmt.Fprintf(os.Stderr, "Unknown sort column: %s (use one of %s)\n", sortBy, strings.Join(columns, ", "))
+		os.Exit(2)
+	}
+
+	finalFilter, timestampTypes := selectFilter(filter, modifiedFilter, accessFilter, ctimeFilter)
+
+	entries, err := matchingEntries(f, dbPath, finalFilter, timestampTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read all the content: %s\n", err)
+		os.Exit(3)
+	}
+
+	// If no results found and a filter was used, show helpful message.
+	if len(entries) == 0 && finalFilter != "" {
+		showFilterHelp(*filter)
+		return
+	}
+
+	sortEntries(entries, less, reverse)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MTIME\tATIME\tCTIME\tCRTIME\tSIZE\tMODE\tUID\tGID\tINODE\tNAME")
+
+	format := "2006-01-02 15:04:05"
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%d\t%s\n",
+			e.ModificationTime.In(displayLocation).Format(format), e.AccessTime.In(displayLocation).Format(format),
+			e.ChangeTime.In(displayLocation).Format(format), e.CreationTime.In(displayLocation).Format(format),
+			e.Size, e.Mode, formatID(e.UID, ownerName(e)), formatID(e.GID, groupName(e)), e.Inode, e.Name)
+	}
+
+	w.Flush()
+}
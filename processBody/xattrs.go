@@ -0,0 +1,154 @@
+package processBody
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/airbus-cert/bodyfile"
+
+	"gobodyfile/common"
+)
+
+// Extended attributes of the entries by path, loaded from an xattrs sidecar.
+var xattrs map[string]*common.XattrRecord
+
+/*
+LoadXattrs reads the xattrs sidecar written by -body -xattrs so the extended attributes are shown
+with the entries and can be filtered on. The sidecar can also be read from a package.
+*/
+func LoadXattrs(path string) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+
+	if common.IsPackage(f) {
+		packaged, err := common.PackageFile(f, ".xattrs.jsonl")
+		if err != nil {
+			return fmt.Errorf("no xattrs sidecar in %s", path)
+		}
+		defer packaged.Close()
+		r = packaged
+	}
+
+	return readXattrs(r)
+}
+
+/*
+Reads the sidecar and keeps its records.
+*/
+func readXattrs(r io.Reader) error {
+
+	records, err := common.ReadXattrs(r)
+	if err != nil {
+		return fmt.Errorf("invalid xattrs sidecar: %v", err)
+	}
+
+	xattrs = records
+
+	return nil
+}
+
+/*
+Loads the xattrs sidecar of a package being processed when -xattrs wasn't given.
+*/
+func loadPackagedXattrs(f *os.File) {
+
+	if xattrs != nil {
+		return
+	}
+
+	packaged, err := common.PackageFile(f, ".xattrs.jsonl")
+	if err != nil {
+		return
+	}
+	defer packaged.Close()
+
+	if err := readXattrs(packaged); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the xattrs sidecar of the package: %s\n", err)
+	}
+}
+
+/*
+Returns the extended attributes of an entry. A record with another inode is for a different file
+that had the same path.
+*/
+func entryXattrs(e *bodyfile.Entry) *common.XattrRecord {
+
+	record, ok := xattrs[e.Name]
+	if !ok || (record.Inode != 0 && e.Inode != 0 && record.Inode != uint64(e.Inode)) {
+		return nil
+	}
+
+	return record
+}
+
+/*
+Returns the ACL entries with the default ones prefixed by default:, as getfacl lists them.
+*/
+func aclEntries(record *common.XattrRecord) []string {
+
+	entries := append([]string{}, record.ACL...)
+	for _, entry := range record.DefaultACL {
+		entries = append(entries, "default:"+entry)
+	}
+
+	return entries
+}
+
+/*
+Adds the filter variables of the extended attributes. They are empty when the entry has none:
+
+	xattrs        space separated attribute names
+	capabilities  file capabilities as getcap shows them (cap_net_raw=ep)
+	selinux       SELinux label
+	acl           comma separated ACL entries (user:1000:rwx,default:group::r-x)
+*/
+func xattrParameters(e *bodyfile.Entry, params govaluate.MapParameters) {
+
+	params["xattrs"] = ""
+	params["capabilities"] = ""
+	params["selinux"] = ""
+	params["acl"] = ""
+
+	record := entryXattrs(e)
+	if record == nil {
+		return
+	}
+
+	params["xattrs"] = strings.Join(record.Names(), " ")
+	params["capabilities"] = record.Capabilities
+	params["selinux"] = record.Labels["selinux"]
+	params["acl"] = strings.Join(aclEntries(record), ",")
+}
+
+/*
+Returns the extended attributes shown after an entry, or an empty string when it has none.
+*/
+func xattrLabel(e *bodyfile.Entry) string {
+
+	record := entryXattrs(e)
+	if record == nil {
+		return ""
+	}
+
+	parts := []string{"xattrs: " + strings.Join(record.Names(), ",")}
+	if record.Capabilities != "" {
+		parts = append(parts, "caps: "+record.Capabilities)
+	}
+	if label := record.Labels["selinux"]; label != "" {
+		parts = append(parts, "selinux: "+label)
+	}
+	if acl := aclEntries(record); len(acl) > 0 {
+		parts = append(parts, "acl: "+strings.Join(acl, ","))
+	}
+
+	return "[" + strings.Join(parts, "; ") + "]"
+}
@@ -0,0 +1,274 @@
+package snapshotBody
+
+/*
+Takes a body file snapshot of a directory on an interval so gobodyfile can run unattended.
+Older snapshots are compressed and removed based on the retention settings, and a state file
+lists the snapshots that are kept so any two can be compared later with -diff.
+*/
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"gobodyfile/createBody"
+)
+
+// Name of the state file in the snapshot directory.
+const stateFile = "snapshots.json"
+
+// Snapshot is a body file taken by the daemon.
+type Snapshot struct {
+	File       string    `json:"file"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Compressed bool      `json:"compressed"`
+}
+
+// State is the list of snapshots kept in the snapshot directory.
+type State struct {
+	Directory string     `json:"directory"`
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+/*
+Loads the state file or returns an empty state when there isn't one yet.
+*/
+func loadState(snapshotDir string) (*State, error) {
+
+	state := &State{}
+
+	data, err := os.ReadFile(filepath.Join(snapshotDir, stateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file: %v", err)
+	}
+
+	return state, nil
+}
+
+/*
+Writes the state file. A temporary file is renamed over it so it is never left half written.
+*/
+func saveState(snapshotDir string, state *State) error {
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(snapshotDir, stateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(snapshotDir, stateFile))
+}
+
+/*
+Compresses a file with gzip and removes the original.
+*/
+func compressFile(path string) error {
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(path)
+
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+/*
+Compresses every snapshot but the newest one.
+*/
+func rotate(snapshotDir string, state *State) {
+
+	for i := range state.Snapshots[:max(0, len(state.Snapshots)-1)] {
+
+		snapshot := &state.Snapshots[i]
+		if snapshot.Compressed {
+			continue
+		}
+
+		if err := compressFile(filepath.Join(snapshotDir, snapshot.File)); err != nil {
+			fmt.Printf("Could not compress %s: %v\n", snapshot.File, err)
+			continue
+		}
+
+		snapshot.File += ".gz"
+		snapshot.Compressed = true
+	}
+}
+
+/*
+Removes the snapshots beyond the retention count or older than the maximum age. The newest
+snapshot is always kept.
+*/
+func enforceRetention(snapshotDir string, state *State, keep int, maxAge time.Duration) {
+
+	var kept []Snapshot
+	now := time.Now()
+
+	for i, snapshot := range state.Snapshots {
+
+		newest := i == len(state.Snapshots)-1
+		tooMany := keep > 0 && len(state.Snapshots)-i > keep
+		tooOld := maxAge > 0 && now.Sub(snapshot.Started) > maxAge
+
+		if newest || (!tooMany && !tooOld) {
+			kept = append(kept, snapshot)
+			continue
+		}
+
+		for _, path := range snapshotFiles(snapshot) {
+			if err := os.Remove(filepath.Join(snapshotDir, path)); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("Could not remove %s: %v\n", path, err)
+			}
+		}
+	}
+
+	state.Snapshots = kept
+}
+
+/*
+Returns the snapshot and the files written next to it.
+*/
+func snapshotFiles(snapshot Snapshot) []string {
+
+	name := snapshot.File
+	if snapshot.Compressed {
+		name = name[:len(name)-len(".gz")]
+	}
+
+	return []string{snapshot.File, name + ".errors.log", name + ".summary.json", name + ".manifest.json"}
+}
+
+/*
+Takes a snapshot and updates the state. Only a snapshot that was written completely is recorded, the
+files of one that failed or was interrupted are removed. Returns false when it was interrupted.
+*/
+func takeSnapshot(rootDir string, snapshotDir string, state *State, keep int, maxAge time.Duration, opts createBody.Options) bool {
+
+	started := time.Now()
+	name := fmt.Sprintf("snapshot-%s.body", started.Format("20060102-150405"))
+	path := filepath.Join(snapshotDir, name)
+
+	// The files of a failed snapshot are removed, so a file that is already there is never reused.
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("Skipping snapshot %s, the file already exists.\n", name)
+		return true
+	}
+
+	fmt.Printf("Taking snapshot %s\n", name)
+	if err := createBody.CreateBody([]string{rootDir}, path, opts); err != nil {
+
+		for _, partial := range append(snapshotFiles(Snapshot{File: name}), name+".checkpoint") {
+			os.Remove(filepath.Join(snapshotDir, partial))
+		}
+
+		if errors.Is(err, createBody.ErrInterrupted) {
+			return false
+		}
+
+		fmt.Printf("Snapshot %s failed: %v\n", name, err)
+		return true
+	}
+
+	state.Snapshots = append(state.Snapshots, Snapshot{File: name, Started: started, Finished: time.Now()})
+	sort.SliceStable(state.Snapshots, func(i, j int) bool {
+		return state.Snapshots[i].Started.Before(state.Snapshots[j].Started)
+	})
+
+	rotate(snapshotDir, state)
+	enforceRetention(snapshotDir, state, keep, maxAge)
+
+	if err := saveState(snapshotDir, state); err != nil {
+		fmt.Printf("Could not write the state file: %v\n", err)
+	}
+
+	return true
+}
+
+/*
+ServeSnapshots takes a snapshot of rootDir every interval until it receives SIGTERM or Ctrl-C.
+A snapshot in progress is stopped and removed before exiting.
+*/
+func ServeSnapshots(rootDir string, snapshotDir string, interval time.Duration, keep int, maxAge time.Duration, opts createBody.Options) {
+
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		fmt.Printf("Could not create the snapshot directory: %v\n", err)
+		return
+	}
+
+	state, err := loadState(snapshotDir)
+	if err != nil {
+		fmt.Printf("Could not read the state file: %v\n", err)
+		return
+	}
+	state.Directory = rootDir
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+
+		if !takeSnapshot(rootDir, snapshotDir, state, keep, maxAge, opts) {
+			fmt.Println("Stopping the snapshots.")
+			return
+		}
+
+		// Check for a signal received during the snapshot before waiting for the next one.
+		select {
+		case <-stop:
+			fmt.Println("Stopping the snapshots.")
+			return
+		default:
+		}
+
+		select {
+		case <-stop:
+			fmt.Println("Stopping the snapshots.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
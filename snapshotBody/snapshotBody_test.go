@@ -0,0 +1,149 @@
+package snapshotBody
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+Writes a snapshot and its side files started at the given time and returns it.
+*/
+func writeSnapshot(t *testing.T, dir string, started time.Time) Snapshot {
+
+	snapshot := Snapshot{File: "snapshot-" + started.Format("20060102-150405") + ".body", Started: started, Finished: started.Add(time.Minute)}
+	for _, name := range snapshotFiles(snapshot) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return snapshot
+}
+
+/*
+Returns the files of the snapshot directory.
+*/
+func listFiles(t *testing.T, dir string) []string {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	state := &State{Snapshots: []Snapshot{writeSnapshot(t, dir, now.Add(-2*time.Hour)), writeSnapshot(t, dir, now)}}
+	first := state.Snapshots[0].File
+
+	rotate(dir, state)
+
+	if !state.Snapshots[0].Compressed || state.Snapshots[0].File != first+".gz" || state.Snapshots[1].Compressed {
+		t.Fatalf("rotate() = %+v, want only the older snapshot compressed", state.Snapshots)
+	}
+	if _, err := os.Stat(filepath.Join(dir, first)); !os.IsNotExist(err) {
+		t.Errorf("rotate() left %s uncompressed", first)
+	}
+
+	f, err := os.Open(filepath.Join(dir, first+".gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != first {
+		t.Errorf("compressed snapshot = %q, want %q", data, first)
+	}
+
+	// The side files stay next to the compressed snapshot so retention removes them with it.
+	if got := snapshotFiles(state.Snapshots[0]); got[1] != first+".errors.log" {
+		t.Errorf("snapshotFiles() of a compressed snapshot = %v", got)
+	}
+}
+
+func TestEnforceRetention(t *testing.T) {
+	now := time.Now()
+	ages := []time.Duration{10 * 24 * time.Hour, 5 * 24 * time.Hour, 2 * 24 * time.Hour, time.Hour}
+
+	tests := []struct {
+		name   string
+		keep   int
+		maxAge time.Duration
+		kept   []int
+	}{
+		{"keep", 2, 0, []int{2, 3}},
+		{"max-age", 0, 72 * time.Hour, []int{2, 3}},
+		{"both", 3, 30 * time.Minute, []int{3}},
+		{"none", 0, 0, []int{0, 1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+
+		dir := t.TempDir()
+		state := &State{}
+		for _, age := range ages {
+			state.Snapshots = append(state.Snapshots, writeSnapshot(t, dir, now.Add(-age)))
+		}
+		all := append([]Snapshot(nil), state.Snapshots...)
+
+		enforceRetention(dir, state, tt.keep, tt.maxAge)
+
+		var want []Snapshot
+		var files []string
+		for _, i := range tt.kept {
+			want = append(want, all[i])
+			files = append(files, snapshotFiles(all[i])...)
+		}
+		if !reflect.DeepEqual(state.Snapshots, want) {
+			t.Errorf("%s: enforceRetention() kept %v, want snapshots %v", tt.name, state.Snapshots, tt.kept)
+		}
+		if got := listFiles(t, dir); len(got) != len(files) {
+			t.Errorf("%s: enforceRetention() left %v, want %v", tt.name, got, files)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+
+	state, err := loadState(dir)
+	if err != nil || len(state.Snapshots) != 0 {
+		t.Fatalf("loadState() without a state file = %+v, %v, want an empty state", state, err)
+	}
+
+	started := time.Date(2025, 6, 19, 13, 0, 0, 0, time.UTC)
+	state = &State{Directory: "/srv", Snapshots: []Snapshot{{File: "snapshot-20250619-130000.body.gz", Started: started, Finished: started.Add(time.Minute), Compressed: true}}}
+	if err := saveState(dir, state); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadState(dir)
+	if err != nil || !reflect.DeepEqual(loaded, state) {
+		t.Errorf("loadState() = %+v, %v, want %+v", loaded, err, state)
+	}
+	if got := listFiles(t, dir); !reflect.DeepEqual(got, []string{stateFile}) {
+		t.Errorf("snapshot directory = %v, want only the state file", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, stateFile), []byte("{"), 0644)
+	if _, err := loadState(dir); err == nil {
+		t.Error("loadState() accepted a broken state file")
+	}
+}
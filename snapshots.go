@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gobodyfile/createBody"
+	"gobodyfile/snapshotBody"
+)
+
+/*
+Takes body file snapshots on an interval until stopped.
+*/
+func runSnapshots(args []string) {
+
+	fs := newFlagSet("snapshots", "", "")
+
+	// Variables for taking snapshots on an interval.
+	var rootDir string
+	var snapshotDir string
+	var interval time.Duration
+	var keep int
+	var maxAge time.Duration
+	var opts createBody.Options
+
+	fs.StringVar(&rootDir, "directory", "", "Directory containing the files to collect metadata.")
+	fs.StringVar(&snapshotDir, "snapshot-dir", "", "Directory the snapshots and state file are written to.")
+	fs.DurationVar(&interval, "interval", 24*time.Hour, "Time between snapshots (e.g., 30m, 6h, 24h).")
+	fs.IntVar(&keep, "keep", 0, "(Optional) Number of snapshots to keep. Default keeps all of them.")
+	fs.DurationVar(&maxAge, "max-age", 0, "(Optional) Remove snapshots older than this (e.g., 720h).")
+	fs.BoolVar(&opts.MD5, "md5", false, "(Optional) Compute the MD5 of regular files.")
+	fs.BoolVar(&opts.SID, "sid", false, "(Optional) Display the SID. Default will return the UID and GID.")
+
+	args = parseFlags(fs, args)
+
+	// Snapshots take no arguments, a directory given without -directory would be ignored.
+	if len(args) > 0 {
+
+		fs.Usage()
+		os.Exit(2)
+
+	}
+
+	// Check if the root value is empty.
+	if rootDir == "" {
+
+		fmt.Println("Directory not provided. Use -directory and provide the directory name.")
+		return
+
+	}
+
+	// Check if the snapshot directory is empty.
+	if snapshotDir == "" {
+
+		fmt.Println("Snapshot directory not provided. Use -snapshot-dir and provide the directory name.")
+		return
+
+	}
+
+	// Take snapshots until stopped.
+	snapshotBody.ServeSnapshots(rootDir, snapshotDir, interval, keep, maxAge, opts)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gobodyfile/common"
+	"gobodyfile/processBody"
+)
+
+/*
+Shows the timeline of a body file, or of an index database with -db.
+*/
+func runTimeline(args []string) {
+
+	fs := newFlagSet("timeline", "[bodyfile.txt | -db case.db | -report <name> bodyfile.txt...]", fmt.Sprintf(`Filter examples:
+  -filter "hour > 12"     (files modified after noon)
+  -filter "hour < 6"      (files modified before 6 AM)
+  -filter "day == 19"     (files modified on the 19th)
+  -filter "weekday == \"Monday\"" (files modified on Monday)
+  -filter "hour >= 9 && hour <= 17" (files modified 9 AM to 5 PM)
+  -filter "date > "%s"" (files modified in last hour)
+  -filter "date > "2025-06-19 13:47:35"" (files modified after specific time)
+  -filter "date > "2025-06-19"" (files modified after specific date)
+  -filter "date > "2025/06/19 13:47:35"" (slash format also supported)
+
+Timestamp-specific filters (only show entries for the specified timestamp type):
+  -modified "date < "2025-06-17"" (filter on modification time only)
+  -access "date > "2025-06-19"" (filter on access time only)
+  -ctime "date == "2025-06-16"" (filter on change time only)
+
+File view (one row per entry with mtime, atime, ctime and crtime side by side):
+  -view files -sort mtime -reverse (newest modifications first)
+  -view files -sort size -filter "date > "2025-06-19"" (filters work the same way)
+
+Activity summary (histogram per MACB type and an hour of day by weekday heatmap):
+  -summary -bucket day (count events per day)
+  -summary -bucket hour -summary-out activity.svg (also export the buckets as CSV or SVG)
+
+Pivot (events around an anchor, with offsets from it):
+  -pivot "/tmp/.x/payload" -window 5m (events within 5 minutes of the file's modification)
+  -pivot "inode:1234" -pivot-type b (center on the creation time of inode 1234)
+  -pivot "2025-06-19 13:47:35" -window 30s (center on an explicit time)
+
+Extended attributes (from the sidecar of collect -xattrs):
+  -xattrs body.txt.xattrs.jsonl -filter "capabilities != \"\"" (files with capabilities)
+  -xattrs body.txt.xattrs.jsonl -filter "selinux =~ \"unconfined_t\"" (SELinux label)
+  -xattrs body.txt.xattrs.jsonl -filter "xattrs =~ \"user\\.\"" (any user.* attribute)
+
+Owners (names from the passwd and group files of the collected system):
+  -owners -filter "owner == \"www-data\"" (files owned by www-data on this system)
+  -owners-from /mnt/evidence -filter "group == \"docker\"" (accounts of a mounted image)
+  -owners-from body.txt.owners.json -view files (sidecar written by collect -owners)
+
+File types (from the type column of collect -types):
+  -filter "ext_mismatch" (files whose extension contradicts their content)
+  -filter "type == \"elf\" && path =~ \"^/tmp/\"" (executables in /tmp)
+  -report mismatches (list the renamed files with their detected type)
+
+Entropy (from the entropy column of collect -entropy, 0 to 8 bits per byte):
+  -filter "entropy > 7.9 && type == \"data\"" (encrypted or packed files)
+  -report entropy -min-entropy 7.8 -gap 5m (high entropy files clustered by directory and time)
+
+Similarity (from the ssdeep column of collect -ssdeep):
+  -filter "similar_to(\"96:s4Ud...:s4Ue...\", 80)" (files matching a known sample with a score of 80 or more)
+  -report similar -similarity 70 host1.body host2.body (groups of near-identical files across body files)
+
+Hash sets (matched against the MD5s of collect -md5):
+  -known-good NSRLFile.txt -hide-known-good (leave out the operating system and package files)
+  -known-bad iocs.md5 -alert-known-bad (alert on stderr for each known bad file)
+  -known-good rds.db -filter "known == \"unknown\"" (only the files in no hash set)
+
+Timezone (times are shown in the collection host's timezone when the body file has a header):
+  -tz UTC (show times and read the filters in UTC)
+  -tz local (use this computer's timezone)
+
+Note: -filter checks ALL timestamp types (access, modification, change, creation).
+      -modified, -access, -ctime check ONLY the specified timestamp type.
+      Use -strict to show only matching timestamps instead of all timestamps for matching files.
+
+IMPORTANT: Both YYYY-MM-DD and YYYY/MM/DD formats are supported for date filters.
+For relative time, use 'date > "YYYY-MM-DD HH:MM:SS"' instead
+`, time.Now().Add(-1*time.Hour).Format("2006-01-02 15:04:05")))
+
+	// Create flags for processing the body file.
+	var strict = fs.Bool("strict", false, "Only show the entries matching the date restrictions")
+	var filter = fs.String("filter", "", "Event filter (e.g., \"hour > 12\", \"day == 19\", \"weekday == \\\"Monday\\\"\")")
+	var modifiedFilter = fs.String("modified", "", "Filter on modification time only (e.g., \"date > \\\"2025-06-17\\\"\")")
+	var accessFilter = fs.String("access", "", "Filter on access time only (e.g., \"date > \\\"2025-06-17\\\"\")")
+	var ctimeFilter = fs.String("ctime", "", "Filter on change time only (e.g., \"date > \\\"2025-06-17\\\"\")")
+	var dbPath = fs.String("db", "", "Query an index database created with the index command instead of a body file.")
+	var view = fs.String("view", "", "Output layout. \"files\" lists one row per entry with all four timestamps.")
+	var sortBy = fs.String("sort", "name", "Column to sort -view files by (name, mtime, atime, ctime, crtime, size, mode, uid, gid, inode).")
+	var reverse = fs.Bool("reverse", false, "Sort -view files in descending order.")
+	var summary = fs.Bool("summary", false, "Print histograms and a heatmap of the filtered events instead of the timeline.")
+	var bucket = fs.String("bucket", "hour", "Bucket size for -summary (minute, hour, day).")
+	var summaryOut = fs.String("summary-out", "", "Export the -summary buckets to a .csv or .svg file.")
+	var force = fs.Bool("force", false, "Replace the -summary-out file when it exists.")
+	var pivot = fs.String("pivot", "", "Show the events around an anchor: a path, inode:<number>, or a date.")
+	var pivotType = fs.String("pivot-type", "m", "Timestamp of the -pivot entry to center on (m, a, c, b).")
+	var window = fs.Duration("window", 5*time.Minute, "Time before and after the -pivot anchor to show (e.g., 30s, 5m, 2h).")
+	var identity = fs.String("identity", "", "age identity file to read an encrypted body file.")
+	var resolveOwners = fs.Bool("owners", false, "Show account and group names with the UIDs and GIDs, read from this system unless -owners-from is given.")
+	var ownersFrom = fs.String("owners-from", "", "Root directory with the etc/passwd and etc/group to resolve owners with (like a mounted image), or an owners sidecar written by collect -owners.")
+	var normalize = fs.Bool("normalize-paths", false, "Read backslashes in names as slashes so Windows paths are filtered and shown like C:/Windows/System32.")
+	var xattrsFile = fs.String("xattrs", "", "xattrs sidecar (<output>.xattrs.jsonl) written by collect -xattrs, to show and filter on extended attributes. Read from the package when processing one.")
+	var reportName = fs.String("report", "", "Print a report built from the extended columns instead of the timeline: mismatches (extensions contradicting the type of collect -types) entropy (high entropy files of collect -entropy clustered by directory and time) or similar (groups of files with similar ssdeep hashes of collect -ssdeep, across all the body files given).")
+	var minEntropy = fs.Float64("min-entropy", 7.5, "Lowest entropy, in bits per byte, of the files in -report entropy.")
+	var similarity = fs.Int("similarity", 80, "Lowest ssdeep score, from 0 to 100, of the files grouped together by -report similar.")
+	var gap = fs.Duration("gap", 10*time.Minute, "Largest gap between the modification times of files in the same -report cluster.")
+	var knownGood, knownBad []string
+	fs.Func("known-good", "Hash set of known good files with MD5s: an NSRL RDS SQLite database, a CSV export like NSRLFile.txt, or a list of hashes like md5sum writes. Can be repeated.", func(path string) error {
+		knownGood = append(knownGood, path)
+		return nil
+	})
+	fs.Func("known-bad", "Hash set of known bad files, in the same formats as -known-good. Can be repeated.", func(path string) error {
+		knownBad = append(knownBad, path)
+		return nil
+	})
+	var hideGood = fs.Bool("hide-known-good", false, "Leave out the files of the -known-good hash sets.")
+	var alertBad = fs.Bool("alert-known-bad", false, "Print an alert on stderr for each file of the -known-bad hash sets, even when the filter doesn't match it.")
+	var tz = fs.String("tz", "", "Timezone to show times in: local, UTC, or a name like Europe/Paris. Defaults to the timezone in the body file header, otherwise UTC. Filters and -pivot dates use it too.")
+
+	args = parseFlags(fs, args)
+
+	// Check the export before reading the input, an existing file is only replaced with -force.
+	if *summaryOut != "" {
+
+		if err := common.CheckOutputFile(*summaryOut, *force, false); err != nil {
+
+			fmt.Println(err)
+			os.Exit(1)
+
+		}
+
+	}
+
+	if *normalize {
+		processBody.NormalizePaths()
+	}
+
+	// Join the extended attributes before the input so a package doesn't load its own sidecar.
+	if *xattrsFile != "" {
+
+		if err := processBody.LoadXattrs(*xattrsFile); err != nil {
+
+			fmt.Printf("Could not read the xattrs sidecar: %v\n", err)
+			os.Exit(1)
+
+		}
+
+	}
+
+	// Load the hash sets before reading the input so its entries are marked.
+	for i, paths := range [][]string{knownGood, knownBad} {
+		for _, path := range paths {
+
+			if err := processBody.LoadHashSet(path, i == 1); err != nil {
+
+				fmt.Printf("Could not read the hash set: %v\n", err)
+				os.Exit(1)
+
+			}
+
+		}
+	}
+
+	if *hideGood {
+		processBody.HideKnownGood()
+	}
+	if *alertBad {
+		processBody.AlertKnownBad()
+	}
+
+	// Resolve the owners with the given accounts, a package's own sidecar is used otherwise.
+	if *ownersFrom != "" || *resolveOwners {
+
+		source := *ownersFrom
+		if source == "" {
+			source = "/"
+		}
+
+		if err := processBody.LoadOwners(source); err != nil {
+
+			fmt.Printf("Could not read the owners: %v\n", err)
+			os.Exit(1)
+
+		}
+
+	}
+
+	// Report on the extended columns of one or more body files instead of printing the timeline.
+	if *reportName != "" {
+
+		opts := processBody.ReportOptions{MinEntropy: *minEntropy, Gap: *gap, Similarity: *similarity}
+		processBody.ProcessReport(args, *dbPath, *reportName, *tz, *identity, opts, filter, modifiedFilter, accessFilter, ctimeFilter)
+		return
+
+	}
+
+	// Read the body file and its header unless the index database is queried.
+	var f io.Reader
+	if *dbPath == "" {
+
+		if len(args) > 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		input := processBody.GetInput(strings.Join(args, " "))
+		if input == nil {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		f = processBody.ReadInput(input, *tz, *identity)
+	} else {
+		processBody.UseTimezone(*tz, nil)
+	}
+
+	// Show the events around an anchor instead of the whole timeline.
+	if *pivot != "" {
+
+		processBody.ProcessPivot(f, *dbPath, *pivot, *pivotType, *window, strict, filter, modifiedFilter, accessFilter, ctimeFilter)
+		return
+
+	}
+
+	// Summarize the events instead of printing the timeline.
+	if *summary {
+
+		processBody.ProcessSummary(f, *dbPath, *bucket, *summaryOut, strict, filter, modifiedFilter, accessFilter, ctimeFilter)
+		return
+
+	}
+
+	// List one row per entry instead of one row per timestamp.
+	if *view != "" {
+
+		if *view != "files" {
+
+			fmt.Printf("Unknown view: %s (use -view files)\n", *view)
+			os.Exit(1)
+
+		}
+
+		processBody.ProcessFiles(f, *dbPath, *sortBy, *reverse, filter, modifiedFilter, accessFilter, ctimeFilter)
+		return
+
+	}
+
+	// Query the index database instead of reading a body file.
+	if *dbPath != "" {
+
+		processBody.ProcessIndex(*dbPath, strict, filter, modifiedFilter, accessFilter, ctimeFilter)
+		return
+
+	}
+
+	// Process the body file.
+	processBody.ProcessBody(f, strict, filter, modifiedFilter, accessFilter, ctimeFilter)
+}
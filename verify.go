@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+
+	"gobodyfile/createBody"
+)
+
+/*
+Checks the hashes of the files listed in a collection manifest.
+*/
+func runVerify(args []string) {
+
+	fs := newFlagSet("verify", "<output>.manifest.json", "")
+	args = parseFlags(fs, args)
+
+	// The manifest is the only argument.
+	if len(args) != 1 {
+
+		fs.Usage()
+		os.Exit(2)
+
+	}
+
+	// Check the hashes of the body file and error log.
+	if err := createBody.VerifyManifest(args[0]); err != nil {
+
+		os.Exit(1)
+
+	}
+}